@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"worklog/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect worklog configuration files",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file...]",
+	Short: "Check config files for schema errors",
+	Long: `Check config files for schema errors, reporting each problem with its line number.
+
+Without arguments, validates the global config file (or the one given with --config)
+and the per-directory ` + config.LocalFile + ` file, whichever exist.`,
+	SilenceUsage: true,
+	RunE:         runConfigValidate,
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	paths := args
+	if len(paths) == 0 {
+		global := config.GlobalPath()
+		if configFlag != "" {
+			global = configFlag
+		}
+		for _, p := range []string{global, config.LocalFile} {
+			if _, err := os.Stat(p); err == nil || configFlag == p {
+				paths = append(paths, p)
+			}
+		}
+		if len(paths) == 0 {
+			fmt.Printf("no config files found (looked for %s and %s)\n", global, config.LocalFile)
+			return nil
+		}
+	}
+
+	invalid := 0
+	for _, path := range paths {
+		errs := config.Validate(path, checkDate)
+		if len(errs) == 0 {
+			fmt.Printf("%s: ok\n", path)
+			continue
+		}
+		invalid++
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d config files are invalid", invalid, len(paths))
+	}
+	return nil
+}
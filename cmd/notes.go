@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"worklog/internal/config"
+	"worklog/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	yesterdayFlag []string
+	todayFlag     []string
+	blockersFlag  []string
+	notesFlag     string
+	editNotesFlag bool
+)
+
+// addNotesFlags registers the flags that add the user's own sections to the
+// report, which every command that prints or publishes one shares.
+func addNotesFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringArrayVar(&yesterdayFlag, "yesterday", nil, `add a line to the report's "Yesterday" section (repeatable)`)
+	f.StringArrayVar(&todayFlag, "today", nil, `add a line to the report's "Today" section (repeatable)`)
+	f.StringArrayVar(&blockersFlag, "blockers", nil, `add a line to the report's "Blockers" section (repeatable)`)
+	f.StringVar(&notesFlag, "notes", "", `file with sections to add to the report, as "Heading:" lines followed by "- item" lines`)
+	f.BoolVar(&editNotesFlag, "edit-notes", false, "write the report's Yesterday, Today and Blockers sections in $VISUAL or $EDITOR")
+}
+
+// userNotes returns the sections from the notes file given with --notes or
+// in the config file, with the lines given with --yesterday, --today and
+// --blockers added, and the standup sections first. A notes file from the
+// config file that does not exist yet adds nothing.
+func userNotes(cmd *cobra.Command, settings config.Settings) ([]report.Note, error) {
+	path := settings.Notes
+	if cmd.Flags().Changed("notes") || path == "" {
+		path = notesFlag
+	}
+
+	var notes []report.Note
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !cmd.Flags().Changed("notes"):
+		case err != nil:
+			return nil, fmt.Errorf("reading notes: %w", err)
+		default:
+			if notes, err = report.ParseNotes(bytes.NewReader(data)); err != nil {
+				return nil, fmt.Errorf("notes file %s: %w", path, err)
+			}
+		}
+	}
+
+	for i, items := range [][]string{yesterdayFlag, todayFlag, blockersFlag} {
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				notes = report.AddNote(notes, report.StandupSections[i], item)
+			}
+		}
+	}
+	sortNotes(notes)
+	return notes, nil
+}
+
+const notesHelp = `# Write the sections to add to the report, e.g.
+#
+# Today:
+#   - finish the cache migration
+#
+# Sections without items are left out, and so are lines starting with "#".
+# Add sections of your own by writing another "Heading:" line.
+`
+
+// editNotes opens notes in the user's editor and returns the edited notes.
+// The standup sections are offered even when empty, and the fetched
+// activity is included as comments for reference.
+func editNotes(notes []report.Note, activity string) ([]report.Note, error) {
+	offered := slices.Clone(notes)
+	for _, heading := range report.StandupSections {
+		if !slices.ContainsFunc(offered, func(n report.Note) bool { return n.Heading == heading }) {
+			offered = append(offered, report.Note{Heading: heading})
+		}
+	}
+	sortNotes(offered)
+
+	var b strings.Builder
+	b.WriteString(notesHelp)
+	b.WriteString("#\n# Activity:\n")
+	for line := range strings.Lines(activity) {
+		b.WriteString(strings.TrimRight("#   "+line, " \n") + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(report.FormatNotes(offered))
+
+	f, err := os.CreateTemp("", "worklog-notes-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(b.String())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Run the editor through the shell so that it may carry arguments,
+	// e.g. "code --wait". Its output goes to stderr, as stdout may be
+	// redirected to a file.
+	c := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stderr, os.Stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("editing notes with %s: %w", editor, err)
+	}
+
+	edited, err := os.Open(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer edited.Close()
+	notes, err = report.ParseNotes(edited)
+	if err != nil {
+		return nil, fmt.Errorf("edited notes: %w", err)
+	}
+	return notes, nil
+}
+
+// sortNotes puts the standup sections first, in their usual order, followed
+// by the others in the order they were given.
+func sortNotes(notes []report.Note) {
+	index := func(n report.Note) int {
+		if i := slices.Index(report.StandupSections, n.Heading); i >= 0 {
+			return i
+		}
+		return len(report.StandupSections)
+	}
+	slices.SortStableFunc(notes, func(a, b report.Note) int {
+		return index(a) - index(b)
+	})
+}
+
+// addNotes sets the report's notes from userNotes, first letting the user
+// edit them with --edit-notes.
+func addNotes(notes []report.Note, events []report.Event, since, until time.Time, opts *report.Options) error {
+	if editNotesFlag {
+		activity, err := report.Generate(events, since, until, "text", *opts)
+		if err != nil {
+			return err
+		}
+		if notes, err = editNotes(notes, activity); err != nil {
+			return err
+		}
+	}
+	opts.Notes = notes
+	return nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"worklog/internal/config"
+	"worklog/internal/provider"
+	"worklog/internal/publish"
+	"worklog/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+var postCmd = &cobra.Command{
+	Use:   "post [target...]",
+	Short: "Publish the report to chat or email targets",
+	Long: `Publish the report to the given targets without printing it.
+
+A target is the name of an entry in the config file's publish section, or a
+publisher type configured through the environment, e.g. "slack" with
+SLACK_WEBHOOK_URL. Without arguments, publishes to every target in the
+config file.`,
+	SilenceUsage: true,
+	RunE:         runPost,
+}
+
+func init() {
+	addReportFlags(postCmd)
+	addNotesFlags(postCmd)
+	postCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "print the payloads instead of sending them")
+	rootCmd.AddCommand(postCmd)
+}
+
+func runPost(cmd *cobra.Command, args []string) error {
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+
+	names := args
+	if len(names) == 0 {
+		names = slices.Sorted(maps.Keys(settings.Publish))
+		if len(names) == 0 {
+			return fmt.Errorf("no publish targets: name one, or add a publish section to the config file")
+		}
+	}
+	targets, err := publishTargets(names, settings.Publish)
+	if err != nil {
+		return err
+	}
+
+	var opts report.Options
+	if opts.GroupBy, err = groupBy(cmd, settings); err != nil {
+		return err
+	}
+	notes, err := userNotes(cmd, settings)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	events, since, until, err := fetchReport(cmd, settings, &opts)
+	if err != nil {
+		return err
+	}
+	events = dropHidden(events)
+	if err := addNotes(notes, events, since, until, &opts); err != nil {
+		return err
+	}
+	if err := publishAll(context.Background(), targets, events, since, until, opts); err != nil {
+		return err
+	}
+	recordRun(started, until, opts)
+	return nil
+}
+
+// target is a configured publisher and the name it was selected by.
+type target struct {
+	name      string
+	publisher publish.Publisher
+}
+
+// publishTargets configures a publisher for each name, which is either a
+// target from the config file or a publisher type. Config file settings
+// fall back to <TYPE>_* environment variables, e.g. SLACK_WEBHOOK_URL.
+func publishTargets(names []string, configured map[string]config.Target) ([]target, error) {
+	var targets []target
+	for _, name := range names {
+		ct, inConfig := configured[name]
+		typ := name
+		if inConfig {
+			typ = ct.Type
+		}
+		p, err := publish.New(typ)
+		if err != nil {
+			if !inConfig {
+				return nil, fmt.Errorf("unknown publish target %q: not in the config file and not a publisher type (%s)",
+					name, strings.Join(publish.Types(), ", "))
+			}
+			return nil, fmt.Errorf("publish target %q: %w", name, err)
+		}
+
+		lookup := provider.EnvLookup(typ, "", os.Getenv)
+		if inConfig {
+			envLookup := lookup
+			lookup = func(key string) string {
+				if v := ct.Lookup(key); v != "" {
+					return v
+				}
+				return envLookup(key)
+			}
+		}
+		if err := p.Configure(lookup); err != nil {
+			return nil, fmt.Errorf("publish target %q: %w", name, err)
+		}
+		targets = append(targets, target{name: name, publisher: p})
+	}
+	return targets, nil
+}
+
+// publishAll sends the report to each target, or prints the payloads with
+// --dry-run. It tries every target before returning the errors.
+func publishAll(ctx context.Context, targets []target, events []report.Event, since, until time.Time, opts report.Options) error {
+	var failed []string
+	for _, t := range targets {
+		if dryRunFlag {
+			payload, err := t.publisher.Payload(events, since, until, opts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+			fmt.Fprintf(os.Stderr, "dry run: payload for %s:\n", t.name)
+			fmt.Println(string(payload))
+			continue
+		}
+		if err := t.publisher.Publish(ctx, events, since, until, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "error: publishing to %s: %v\n", t.name, err)
+			failed = append(failed, t.name)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "published to %s\n", t.name)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("publishing failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
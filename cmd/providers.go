@@ -0,0 +1,12 @@
+package cmd
+
+// Activity sources register themselves with the provider registry when
+// imported. Adding a backend only requires adding its import here.
+import (
+	_ "worklog/internal/bitbucket"
+	_ "worklog/internal/gitea"
+	_ "worklog/internal/github"
+	_ "worklog/internal/gitlab"
+	_ "worklog/internal/jira"
+	_ "worklog/internal/localgit"
+)
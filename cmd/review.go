@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"worklog/internal/report"
+	"worklog/internal/review"
+	"worklog/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Curate the report in a terminal UI before printing or publishing it",
+	Long: `Fetch the report and open it in a terminal UI, in which items can be hidden,
+retitled and reordered within their category, and notes such as "Blockers" or
+"Plan for today" can be added. When done, the curated report is printed in the
+chosen output format and published to the --post targets.
+
+Hidden items are remembered in the local store and left out of later reports,
+including plain worklog and worklog post runs. They start hidden the next time
+they are reviewed, where they can be shown again.`,
+	SilenceUsage: true,
+	RunE:         runReview,
+}
+
+func init() {
+	addReportFlags(reviewCmd)
+	addOutputFlags(reviewCmd)
+	addNotesFlags(reviewCmd)
+	rootCmd.AddCommand(reviewCmd)
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	if err := review.Check(os.Stdin, os.Stderr); err != nil {
+		return err
+	}
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	format, opts, err := outputOptions(cmd, settings)
+	if err != nil {
+		return err
+	}
+	targets, err := publishTargets(postFlag, settings.Publish)
+	if err != nil {
+		return err
+	}
+	notes, err := userNotes(cmd, settings)
+	if err != nil {
+		return err
+	}
+
+	hidden, err := hiddenEvents()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: reading hidden items: %v\n", err)
+	}
+	started := time.Now()
+	events, since, until, err := fetchReport(cmd, settings, &opts)
+	if err != nil {
+		return err
+	}
+
+	if err := addNotes(notes, events, since, until, &opts); err != nil {
+		return err
+	}
+
+	res, err := review.Run(os.Stdin, os.Stderr, report.Title(since, until, opts), events, opts.Notes, hidden)
+	if err != nil {
+		return err
+	}
+	if err := setHiddenEvents(res.Hidden); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: saving hidden items: %v\n", err)
+	}
+	opts.Notes = res.Notes
+
+	output, err := report.Generate(res.Events, since, until, format, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Print(output)
+
+	if err := publishAll(context.Background(), targets, res.Events, since, until, opts); err != nil {
+		return err
+	}
+	recordRun(started, until, opts)
+	return nil
+}
+
+// hiddenEvents returns the line keys of the events hidden in earlier
+// reviews.
+func hiddenEvents() (map[string]bool, error) {
+	st, err := store.Open(store.DefaultPath())
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+	return st.Hidden()
+}
+
+// dropHidden removes the events hidden in earlier reviews from a report.
+func dropHidden(events []report.Event) []report.Event {
+	hidden, err := hiddenEvents()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: reading hidden items: %v\n", err)
+		return events
+	}
+	return slices.DeleteFunc(events, func(e report.Event) bool {
+		return hidden[e.LineKey()]
+	})
+}
+
+func setHiddenEvents(hidden map[string]bool) error {
+	st, err := store.Open(store.DefaultPath())
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	return st.SetHidden(hidden, time.Now())
+}
@@ -2,14 +2,21 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"text/template"
 	"time"
 
-	"worklog/internal/github"
-	"worklog/internal/gitlab"
+	"worklog/internal/calendar"
+	"worklog/internal/config"
+	"worklog/internal/provider"
 	"worklog/internal/report"
+	"worklog/internal/store"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
@@ -17,21 +24,89 @@ import (
 )
 
 var (
-	sinceFlag  string
-	untilFlag  string
-	outputFlag string
+	sinceFlag   string
+	untilFlag   string
+	outputFlag  string
+	configFlag  string
+	profileFlag string
+
+	collapseAfterFlag  int
+	templateFlag       string
+	templateStringFlag string
+
+	postFlag   []string
+	dryRunFlag bool
+
+	noCacheFlag bool
+	refreshFlag bool
+	offlineFlag bool
+
+	githubBackendFlag string
+	commitsFlag       string
+	groupByFlag       string
+	tzFlag            string
+	sprintFlag        string
+
+	includeRepoFlag []string
+	excludeRepoFlag []string
+	includeOrgFlag  []string
+	excludeOrgFlag  []string
+
+	// providerFlags holds the --<provider> enable flags, keyed by provider name.
+	providerFlags = make(map[string]*bool)
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "worklog",
-	Short: "Generate a standup report from GitHub and GitLab activity",
+	Short: "Generate a standup report from your code hosting and issue tracker activity",
 	RunE:  run,
 }
 
 func init() {
-	rootCmd.Flags().StringVar(&sinceFlag, "since", "", `start date inclusive, e.g. "2026-01-28", "yesterday", "2 weeks ago" (default: 7 days ago)`)
-	rootCmd.Flags().StringVar(&untilFlag, "until", "", `end date inclusive, e.g. "2026-02-04", "today", "last friday" (default: today)`)
-	rootCmd.Flags().StringVarP(&outputFlag, "output", "o", "text", `output format: "text", "table", or "json"`)
+	addReportFlags(rootCmd)
+	addOutputFlags(rootCmd)
+	addNotesFlags(rootCmd)
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "config file (default: "+config.GlobalPath()+")")
+}
+
+// addOutputFlags registers the flags that choose how the report is printed
+// and where else it is published, which the root command shares with
+// worklog review.
+func addOutputFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVarP(&outputFlag, "output", "o", "text", `output format: "text", "table", "json", "markdown", or "template"`)
+	f.IntVar(&collapseAfterFlag, "collapse-after", 0, "in markdown output, collapse categories with more than this many items into <details> (0: never)")
+	f.StringVar(&templateFlag, "template", "", "template for -o template: a built-in name ("+strings.Join(report.TemplateNames(), ", ")+") or a file path")
+	f.StringVar(&templateStringFlag, "template-string", "", `inline template for -o template, e.g. '{{range .Events}}{{.Title}}{{"\n"}}{{end}}'`)
+	f.StringSliceVar(&postFlag, "post", nil, `also publish the report to these targets: names from the config file's publish section, or "slack"/"mattermost" configured by environment (repeatable)`)
+	f.BoolVar(&dryRunFlag, "dry-run", false, "with --post, print the payloads instead of sending them")
+}
+
+// addReportFlags registers the flags that select and filter activity, which
+// the root command shares with the commands that publish a report.
+func addReportFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVar(&sinceFlag, "since", "", `start date inclusive, e.g. "2026-01-28", "yesterday", "2 weeks ago", "last-workday", "since-last-run" (default: 7 days ago)`)
+	f.StringVar(&untilFlag, "until", "", `end date inclusive, e.g. "2026-02-04", "today", "last friday", "last-week" (default: today)`)
+	f.StringSliceVar(&includeRepoFlag, "include-repo", nil, `only report repos matching these globs, e.g. "acme/*" (repeatable)`)
+	f.StringSliceVar(&excludeRepoFlag, "exclude-repo", nil, `skip repos matching these globs, e.g. "me/dotfiles" (repeatable)`)
+	f.StringSliceVar(&includeOrgFlag, "include-org", nil, "only report repos of organizations matching these globs (repeatable)")
+	f.StringSliceVar(&excludeOrgFlag, "exclude-org", nil, "skip repos of organizations matching these globs (repeatable)")
+	f.StringVar(&commitsFlag, "commits", "nested", `how to show commits that belong to a pull request: "nested" under it, "flat" in the Commits category, or "hidden"`)
+	f.StringVar(&groupByFlag, "group-by", "category", `group the report by "category", "day", "repo" or "source", optionally followed by a second level, e.g. "day,category"`)
+	f.StringVar(&sprintFlag, "sprint", "", `report on a sprint of the config file's sprint calendar: "current", "previous" or a sprint number, instead of --since and --until`)
+	f.StringVar(&tzFlag, "tz", "", `timezone for date ranges, days and displayed times, e.g. "Europe/Berlin" or "UTC" (default: local)`)
+	f.StringVar(&profileFlag, "profile", "", "config profile to apply, e.g. \"weekly\"")
+	f.BoolVar(&noCacheFlag, "no-cache", false, "fetch everything from the providers without reading or updating the local event cache")
+	f.BoolVar(&refreshFlag, "refresh", false, "fetch the whole date range again and update the local event cache")
+	f.BoolVar(&offlineFlag, "offline", false, "report from the local event cache without contacting any provider")
+	f.StringVar(&githubBackendFlag, "github-backend", "", `GitHub API to read activity from: "rest" or "graphql" (default: GITHUB_BACKEND, else "rest")`)
+	for _, name := range provider.Names() {
+		if providerFlags[name] == nil {
+			providerFlags[name] = new(bool)
+		}
+		f.BoolVar(providerFlags[name], name, true, fmt.Sprintf("include %s activity when configured", name))
+	}
 }
 
 func Execute() error {
@@ -39,108 +114,617 @@ func Execute() error {
 }
 
 func run(cmd *cobra.Command, args []string) error {
+	settings, err := loadSettings()
+	if err != nil {
+		return err
+	}
+
+	format, opts, err := outputOptions(cmd, settings)
+	if err != nil {
+		return err
+	}
+	targets, err := publishTargets(postFlag, settings.Publish)
+	if err != nil {
+		return err
+	}
+	notes, err := userNotes(cmd, settings)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	events, since, until, err := fetchReport(cmd, settings, &opts)
+	if err != nil {
+		return err
+	}
+	events = dropHidden(events)
+	if err := addNotes(notes, events, since, until, &opts); err != nil {
+		return err
+	}
+
+	output, err := report.Generate(events, since, until, format, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Print(output)
+
+	if err := publishAll(context.Background(), targets, events, since, until, opts); err != nil {
+		return err
+	}
+	recordRun(started, until, opts)
+	return nil
+}
+
+// outputOptions resolves the output format and the report options that
+// the output flags and the config file set.
+func outputOptions(cmd *cobra.Command, settings config.Settings) (string, report.Options, error) {
+	// Flags given on the command line take precedence over the config file.
+	format := settings.Output
+	if cmd.Flags().Changed("output") || format == "" {
+		format = outputFlag
+	}
+
+	if !slices.Contains(report.Formats, format) {
+		return "", report.Options{}, fmt.Errorf("invalid output format %q: must be one of %s", format, strings.Join(report.Formats, ", "))
+	}
+
+	opts := report.Options{CollapseAfter: collapseAfterFlag}
+	var err error
+	if opts.GroupBy, err = groupBy(cmd, settings); err != nil {
+		return "", report.Options{}, err
+	}
+	if format == "template" {
+		// Parse the template before fetching so that mistakes fail fast.
+		opts.Template, err = outputTemplate(cmd, settings)
+		if err != nil {
+			return "", report.Options{}, err
+		}
+	}
+	return format, opts, nil
+}
+
+// location resolves the report's timezone from --tz or the config file,
+// defaulting to the local one.
+func location(cmd *cobra.Command, settings config.Settings) (*time.Location, error) {
+	name := settings.Timezone
+	if cmd.Flags().Changed("tz") || name == "" {
+		name = tzFlag
+	}
+	if name == "" {
+		// LoadLocation("") is UTC, not the local zone.
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --tz %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// reportRange resolves the report's date range from --sprint, or else from
+// the --since and --until values. Reports on a sprint are named after it in
+// opts.
+func reportRange(cmd *cobra.Command, sinceStr, untilStr string, dc dateContext, opts *report.Options) (time.Time, time.Time, error) {
+	if !cmd.Flags().Changed("sprint") {
+		since, until, err := parseDateRange(sinceStr, untilStr, dc)
+		if err == nil && sinceStr == "this-sprint" {
+			sp, _ := selectSprint("current", dc)
+			opts.Sprint = sp.Name
+		}
+		return since, until, err
+	}
+
+	if cmd.Flags().Changed("since") || cmd.Flags().Changed("until") {
+		return time.Time{}, time.Time{}, fmt.Errorf("--sprint cannot be combined with --since or --until")
+	}
+	sp, err := selectSprint(sprintFlag, dc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid --sprint %q: %w", sprintFlag, err)
+	}
+	opts.Sprint = sp.Name
+	return sp.First, endOfDay(sp.Last), nil
+}
+
+// workCalendar builds the calendar that range keywords are resolved
+// against from the config file.
+func workCalendar(settings config.Settings) (calendar.Calendar, error) {
+	var cal calendar.Calendar
+	var err error
+	if cal.WorkWeek, err = calendar.ParseWorkWeek(settings.WorkWeek); err != nil {
+		return cal, fmt.Errorf("invalid work_week: %w", err)
+	}
+	if settings.Holidays != "" {
+		if cal.Holidays, err = calendar.LoadHolidays(settings.Holidays); err != nil {
+			return cal, fmt.Errorf("invalid holidays: %w", err)
+		}
+	}
+	if settings.Sprint != nil {
+		if cal.Sprints, err = settings.Sprint.Calendar(); err != nil {
+			return cal, fmt.Errorf("invalid sprint: %w", err)
+		}
+	}
+	return cal, nil
+}
+
+// lastRun returns the time of the last successful report from the local
+// store, and whether one was recorded.
+func lastRun() (time.Time, bool, error) {
+	st, err := store.Open(store.DefaultPath())
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer st.Close()
+	return st.LastRun()
+}
+
+// recordRun stores started, the time the report was fetched, as the last
+// run for since-last-run. Reports that end in the past, come from the cache
+// or were only dry runs are not recorded, as they may not show everything
+// up to now.
+func recordRun(started, until time.Time, opts report.Options) {
+	if until.Before(started) || opts.Offline || dryRunFlag {
+		return
+	}
+	st, err := store.Open(store.DefaultPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: recording the last run: %v\n", err)
+		return
+	}
+	defer st.Close()
+	if err := st.SetLastRun(started); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: recording the last run: %v\n", err)
+	}
+}
+
+// groupBy resolves the report grouping from --group-by or the config file.
+// Grouping by category alone is the default layout and returns nil.
+func groupBy(cmd *cobra.Command, settings config.Settings) ([]string, error) {
+	value := settings.GroupBy
+	if cmd.Flags().Changed("group-by") || value == "" {
+		value = groupByFlag
+	}
+	by, err := report.ParseGroupBy(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --group-by: %w", err)
+	}
+	if len(by) == 1 {
+		return nil, nil
+	}
+	return by, nil
+}
+
+// loadSettings reads the .env file and the config files and resolves the
+// --profile settings.
+func loadSettings() (config.Settings, error) {
 	// Load .env file without overriding existing env vars.
 	// Precedence: real env vars > .env file values.
 	_ = godotenv.Load()
 
-	since, until, err := parseDateRange(sinceFlag, untilFlag)
+	cfg, err := loadConfig()
 	if err != nil {
-		return err
+		return config.Settings{}, err
+	}
+	return cfg.Resolve(profileFlag)
+}
+
+// fetchReport fetches the events in the date range from every configured
+// account, or reads them from the cache with --offline, and applies the
+// report-wide processing and filters. Errors from individual accounts are
+// printed as warnings. Offline reports are marked as such in opts.
+func fetchReport(cmd *cobra.Command, settings config.Settings, opts *report.Options) ([]report.Event, time.Time, time.Time, error) {
+	sinceStr, untilStr := settings.Since, settings.Until
+	if cmd.Flags().Changed("since") || sinceStr == "" {
+		sinceStr = sinceFlag
+	}
+	if cmd.Flags().Changed("until") || untilStr == "" {
+		untilStr = untilFlag
+	}
+
+	loc, err := location(cmd, settings)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	cal, err := workCalendar(settings)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	dc := dateContext{now: time.Now().In(loc), calendar: cal, lastRun: lastRun}
+	since, until, err := reportRange(cmd, sinceStr, untilStr, dc, opts)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+
+	commits := settings.Commits
+	if cmd.Flags().Changed("commits") || commits == "" {
+		commits = commitsFlag
+	}
+	if !slices.Contains(report.CommitModes, commits) {
+		return nil, time.Time{}, time.Time{}, fmt.Errorf("invalid --commits %q: must be one of %s", commits, strings.Join(report.CommitModes, ", "))
+	}
+
+	filter := repoFilter(cmd, settings)
+
+	accounts, err := configuredAccounts(settings.Accounts)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	for _, a := range accounts {
+		if f, ok := a.Provider.(provider.RepoFilterer); ok {
+			f.SetRepoFilter(filter)
+		}
+	}
+	if len(accounts) == 0 {
+		return nil, time.Time{}, time.Time{}, fmt.Errorf("no providers configured: set GITHUB_TOKEN, GITLAB_TOKEN, GITEA_TOKEN, BITBUCKET_TOKEN, JIRA_TOKEN or LOCALGIT_DIRS")
 	}
 
-	githubToken := os.Getenv("GITHUB_TOKEN")
-	gitlabToken := os.Getenv("GITLAB_TOKEN")
+	if offlineFlag {
+		if noCacheFlag || refreshFlag {
+			return nil, time.Time{}, time.Time{}, fmt.Errorf("--offline cannot be combined with --no-cache or --refresh")
+		}
+		events, syncs, warnings, err := cachedEvents(accounts, filter, since, until)
+		if err != nil {
+			return nil, time.Time{}, time.Time{}, err
+		}
+		opts.Offline = true
+		opts.Warnings = append(opts.Warnings, warnings...)
+		for i := range syncs {
+			syncs[i].SyncedAt = syncs[i].SyncedAt.In(loc)
+		}
+		opts.Syncs = syncs
+		return processEvents(events, filter, settings, commits, loc), since, until, nil
+	}
 
-	if githubToken == "" && gitlabToken == "" {
-		return fmt.Errorf("at least one of GITHUB_TOKEN or GITLAB_TOKEN must be set")
+	st := openStore()
+	if st != nil {
+		defer st.Close()
 	}
+	scope := cacheScope(filter)
 
 	ctx := context.Background()
 	var allEvents []report.Event
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	var errs []error
+	// Warnings are kept per account so that they are listed in a stable
+	// order.
+	warnings := make([][]string, len(accounts))
 
-	if githubToken != "" {
+	for i, a := range accounts {
 		wg.Go(func() {
-			events, err := github.FetchEvents(ctx, githubToken, since, until)
-			mu.Lock()
-			defer mu.Unlock()
-			if err != nil {
-				errs = append(errs, fmt.Errorf("github: %w", err))
-				return
+			fetch := func(ctx context.Context, since, until time.Time) ([]report.Event, []string, error) {
+				events, err := a.Provider.FetchEvents(ctx, since, until)
+				for i := range events {
+					events[i].Account = a.Label
+				}
+				var warnings []string
+				if w, ok := a.Provider.(provider.Warner); ok {
+					warnings = w.Warnings()
+				}
+				return events, warnings, err
+			}
+
+			var events []report.Event
+			var msgs []string
+			var err error
+			if st == nil {
+				events, msgs, err = fetch(ctx, since, until)
+			} else {
+				if refreshFlag {
+					err = st.Forget(a.CacheKey(), scope)
+				}
+				if err == nil {
+					events, msgs, err = st.Fetch(ctx, a.CacheKey(), scope, fetch, since, until, time.Now())
+				}
 			}
-			allEvents = append(allEvents, events...)
-		})
-	}
 
-	if gitlabToken != "" {
-		wg.Go(func() {
-			events, err := gitlab.FetchEvents(ctx, gitlabToken, since, until)
 			mu.Lock()
 			defer mu.Unlock()
 			if err != nil {
-				errs = append(errs, fmt.Errorf("gitlab: %w", err))
+				errs = append(errs, fmt.Errorf("%s: %w", a, err))
 				return
 			}
 			allEvents = append(allEvents, events...)
+			warnings[i] = msgs
 		})
 	}
 
 	wg.Wait()
-
 	for _, err := range errs {
 		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
 	}
+	for i, a := range accounts {
+		for _, msg := range warnings[i] {
+			opts.Warnings = append(opts.Warnings, fmt.Sprintf("%s: %s", a, msg))
+		}
+	}
+	return processEvents(allEvents, filter, settings, commits, loc), since, until, nil
+}
+
+// processEvents applies the report-wide processing and filters to the
+// events of all accounts, with their times in loc. Commits are grouped under
+// their pull requests after filtering, so that disabling either category
+// leaves the other intact, and repeated actions are merged last.
+func processEvents(events []report.Event, filter report.RepoFilter, settings config.Settings, commits string, loc *time.Location) []report.Event {
+	events = report.InLocation(events, loc)
+	events = report.DedupeCommits(events)
+	events = report.LinkTickets(events)
+	events = report.FilterRepos(events, filter)
+	events = report.FilterCategories(events, disabledCategories(settings.Categories))
+	events = report.GroupCommits(events, commits)
+	return report.Aggregate(events, disabledCategories(settings.Aggregate))
+}
 
-	switch outputFlag {
-	case "text", "table", "json":
-	default:
-		return fmt.Errorf("invalid output format %q: must be one of \"text\", \"table\", \"json\"", outputFlag)
+// cachedEvents reads the accounts' events from the local cache and reports
+// when each was last synced, with the warnings of the syncs that covered
+// the range. A sync with the current repo filter is preferred, falling back
+// to an unfiltered one, which covers every filter.
+func cachedEvents(accounts []provider.Account, filter report.RepoFilter, since, until time.Time) ([]report.Event, []report.SyncStatus, []string, error) {
+	st, err := store.Open(store.DefaultPath())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("opening the event cache for --offline: %w", err)
 	}
+	defer st.Close()
 
-	output := report.Generate(allEvents, since, until, outputFlag)
-	fmt.Print(output)
-	return nil
+	var events []report.Event
+	var syncs []report.SyncStatus
+	var warnings []string
+	for _, a := range accounts {
+		scope := cacheScope(filter)
+		cur, ok, err := st.Cursor(a.CacheKey(), scope)
+		if err == nil && !ok && scope != "" {
+			scope = ""
+			cur, ok, err = st.Cursor(a.CacheKey(), scope)
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		syncs = append(syncs, report.SyncStatus{Account: a.String(), SyncedAt: cur.SyncedAt})
+		if !ok {
+			fmt.Fprintf(os.Stderr, "warning: %s: no cached events; run once without --offline\n", a)
+			continue
+		}
+		if since.Before(cur.From) {
+			fmt.Fprintf(os.Stderr, "warning: %s: cache only goes back to %s\n", a, cur.From.Format(dateFormat))
+		}
+
+		accountEvents, err := st.Events(a.CacheKey(), scope, since, until)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		events = append(events, accountEvents...)
+		for _, msg := range cur.WarningsBetween(since, until) {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", a, msg))
+		}
+	}
+	return events, syncs, warnings, nil
+}
+
+// openStore opens the local event cache, or returns nil with --no-cache or
+// when the cache cannot be opened, in which case everything is fetched.
+func openStore() *store.Store {
+	if noCacheFlag {
+		return nil
+	}
+	st, err := store.Open(store.DefaultPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: event cache unavailable, fetching everything: %v\n", err)
+		return nil
+	}
+	return st
+}
+
+// cacheScope identifies the repo filter in the event cache. The filter is
+// pushed down to providers, so a sync with one filter does not cover the
+// range for another.
+func cacheScope(f report.RepoFilter) string {
+	if f.Empty() {
+		return ""
+	}
+	data, _ := json.Marshal(f)
+	return string(data)
+}
+
+// outputTemplate returns the template given with --template-string or
+// --template, falling back to the template named in the config file.
+func outputTemplate(cmd *cobra.Command, settings config.Settings) (*template.Template, error) {
+	if cmd.Flags().Changed("template-string") {
+		return report.ParseTemplate("template-string", templateStringFlag)
+	}
+	name := settings.Template
+	if cmd.Flags().Changed("template") {
+		name = templateFlag
+	}
+	if name == "" {
+		return nil, fmt.Errorf("-o template needs --template or --template-string")
+	}
+	return report.LoadTemplate(name)
+}
+
+// loadConfig reads the global config file, or the one given with --config,
+// followed by the per-directory override.
+func loadConfig() (*config.Config, error) {
+	global := config.GlobalPath()
+	if configFlag != "" {
+		if _, err := os.Stat(configFlag); err != nil {
+			return nil, err
+		}
+		global = configFlag
+	}
+	cfg, err := config.Load(checkDate, global, config.LocalFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	return cfg, nil
+}
+
+// checkDate validates a since or until value from a config file.
+func checkDate(s string) error {
+	if slices.Contains(rangeKeywords, s) {
+		return nil
+	}
+	_, err := parseDate(s, time.Now())
+	return err
+}
+
+// configuredAccounts returns an account for every registered provider that
+// is enabled by its --<provider> flag and finds enough configuration: the
+// default account, each named account listed in <PROVIDER>_ACCOUNTS, and each
+// account in the config file. Config file settings take precedence over
+// environment variables for the same account. A listed account that is not
+// configured is an error.
+func configuredAccounts(fromConfig []config.Account) ([]provider.Account, error) {
+	var accounts []provider.Account
+	for _, name := range provider.Names() {
+		if !*providerFlags[name] {
+			continue
+		}
+
+		labels := append([]string{""}, provider.EnvAccounts(name, os.Getenv)...)
+		configured := make(map[string]config.Account)
+		for _, ca := range fromConfig {
+			if ca.Provider != name {
+				continue
+			}
+			if _, ok := configured[ca.Label]; !ok && !slices.Contains(labels, ca.Label) {
+				labels = append(labels, ca.Label)
+			}
+			configured[ca.Label] = ca
+		}
+
+		for _, label := range labels {
+			p, _ := provider.New(name)
+			a := provider.Account{Label: label, Provider: p}
+			envLookup := provider.EnvLookup(name, label, os.Getenv)
+			lookup := envLookup
+			ca, inConfig := configured[label]
+			if inConfig {
+				lookup = func(key string) string {
+					if v := ca.Lookup(key); v != "" {
+						return v
+					}
+					return envLookup(key)
+				}
+			}
+			if name == "github" && githubBackendFlag != "" {
+				accountLookup := lookup
+				lookup = func(key string) string {
+					if key == "BACKEND" {
+						return githubBackendFlag
+					}
+					return accountLookup(key)
+				}
+			}
+			ok, err := p.Configure(lookup)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", a, err)
+			}
+			if !ok {
+				switch {
+				case inConfig:
+					return nil, fmt.Errorf("%s: account in config file is not configured", a)
+				case label != "":
+					return nil, fmt.Errorf("%s: account is listed in %s_ACCOUNTS but not configured", a, strings.ToUpper(name))
+				}
+				continue
+			}
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts, nil
+}
+
+// repoFilter builds the repo filter from the config file patterns, each
+// list replaced by its command-line flag when given.
+func repoFilter(cmd *cobra.Command, settings config.Settings) report.RepoFilter {
+	f := report.RepoFilter{
+		IncludeRepos: settings.Repos.Include,
+		ExcludeRepos: settings.Repos.Exclude,
+		IncludeOrgs:  settings.Orgs.Include,
+		ExcludeOrgs:  settings.Orgs.Exclude,
+	}
+	if cmd.Flags().Changed("include-repo") {
+		f.IncludeRepos = includeRepoFlag
+	}
+	if cmd.Flags().Changed("exclude-repo") {
+		f.ExcludeRepos = excludeRepoFlag
+	}
+	if cmd.Flags().Changed("include-org") {
+		f.IncludeOrgs = includeOrgFlag
+	}
+	if cmd.Flags().Changed("exclude-org") {
+		f.ExcludeOrgs = excludeOrgFlag
+	}
+	return f
+}
+
+// disabledCategories converts config category toggles, keyed by slug, into
+// the set of categories to leave out of the report.
+func disabledCategories(toggles map[string]bool) map[report.EventCategory]bool {
+	disabled := make(map[report.EventCategory]bool)
+	for slug, enabled := range toggles {
+		if cat, ok := report.ParseCategory(slug); ok && !enabled {
+			disabled[cat] = true
+		}
+	}
+	return disabled
 }
 
 const dateFormat = "2006-01-02"
 
+// rangeKeywords name spans of time relative to the calendar and the last
+// run. In --since they stand for the start of the span, in --until for its
+// end.
+var rangeKeywords = []string{"last-workday", "last-week", "this-sprint", "since-last-run"}
+
+// dateContext is what dates and range keywords are resolved against.
+type dateContext struct {
+	now      time.Time // in the report's timezone
+	calendar calendar.Calendar
+	lastRun  func() (time.Time, bool, error)
+}
+
 // parseDateRange resolves the --since and --until flag values into a [since, until] time range.
 //
-// Both flags accept either an exact date (YYYY-MM-DD) or a natural language expression
-// such as "yesterday", "2 weeks ago", or "last monday". Exact dates are tried first;
-// if parsing fails, the input is interpreted as natural language relative to the current time.
+// Both flags accept either an exact date (YYYY-MM-DD), a natural language expression
+// such as "yesterday", "2 weeks ago", or "last monday", or one of the rangeKeywords.
+// Exact dates are tried first; if parsing fails, the input is interpreted as natural
+// language relative to the current time.
 //
 // Both boundaries are inclusive:
 //   - --since is normalized to the start of the resolved day (00:00:00).
 //   - --until is normalized to the end of the resolved day (23:59:59).
 //
+// The exception is since-last-run, which starts at the exact time of the last run.
+// Days and relative expressions are resolved in the location of dc.now, the
+// report's timezone.
+//
 // Defaults when omitted: --since = 7 days ago, --until = today.
-func parseDateRange(sinceStr, untilStr string) (time.Time, time.Time, error) {
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+func parseDateRange(sinceStr, untilStr string, dc dateContext) (time.Time, time.Time, error) {
+	today := startOfDay(dc.now)
 
 	var since time.Time
 	if sinceStr == "" {
 		since = today.AddDate(0, 0, -7)
 	} else {
-		t, err := parseDate(sinceStr, now)
+		t, err := resolveDate(sinceStr, dc, false)
 		if err != nil {
 			return time.Time{}, time.Time{}, fmt.Errorf("invalid --since value %q: %w", sinceStr, err)
 		}
-		since = startOfDay(t)
+		since = t
 	}
 
 	var until time.Time
 	if untilStr == "" {
 		until = endOfDay(today)
 	} else {
-		t, err := parseDate(untilStr, now)
+		t, err := resolveDate(untilStr, dc, true)
 		if err != nil {
 			return time.Time{}, time.Time{}, fmt.Errorf("invalid --until value %q: %w", untilStr, err)
 		}
-		until = endOfDay(t)
+		until = t
 	}
 
 	if since.After(until) {
@@ -151,6 +735,92 @@ func parseDateRange(sinceStr, untilStr string) (time.Time, time.Time, error) {
 	return since, until, nil
 }
 
+// resolveDate resolves a --since value to the start of the day or span it
+// names, or with end set, an --until value to the end of it.
+func resolveDate(s string, dc dateContext, end bool) (time.Time, error) {
+	if slices.Contains(rangeKeywords, s) {
+		first, last, err := keywordRange(s, dc)
+		if end {
+			return last, err
+		}
+		return first, err
+	}
+	t, err := parseDate(s, dc.now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		return endOfDay(t), nil
+	}
+	return startOfDay(t), nil
+}
+
+// keywordRange returns the span a range keyword names:
+//   - last-workday: the last working day before today, skipping days off
+//     and holidays.
+//   - last-week: Monday to Sunday of the previous week.
+//   - this-sprint: the first to the last day of the current sprint.
+//   - since-last-run: the last successful report until now, or the last
+//     working day if none was recorded yet.
+func keywordRange(s string, dc dateContext) (time.Time, time.Time, error) {
+	today := startOfDay(dc.now)
+	switch s {
+	case "last-workday":
+		day := dc.calendar.PrevWorkday(dc.now)
+		return day, endOfDay(day), nil
+	case "last-week":
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return monday.AddDate(0, 0, -7), endOfDay(monday.AddDate(0, 0, -1)), nil
+	case "this-sprint":
+		sp, err := selectSprint("current", dc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return sp.First, endOfDay(sp.Last), nil
+	case "since-last-run":
+		t, ok, err := dc.lastRun()
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("reading the last run: %w", err)
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "warning: no previous run recorded, reporting since the last working day\n")
+			return keywordRange("last-workday", dc)
+		}
+		return t.In(dc.now.Location()), dc.now, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown range keyword %q", s)
+}
+
+// selectSprint returns the sprint a --sprint value names: "current",
+// "previous" or a sprint number.
+func selectSprint(s string, dc dateContext) (calendar.Sprint, error) {
+	sprints := dc.calendar.Sprints
+	if sprints == nil {
+		return calendar.Sprint{}, fmt.Errorf("no sprint calendar: add a sprint section to the config file")
+	}
+	switch s {
+	case "current":
+		if sp, ok := sprints.Current(dc.now); ok {
+			return sp, nil
+		}
+		return calendar.Sprint{}, fmt.Errorf("today is not in any sprint of the sprint list")
+	case "previous":
+		if sp, ok := sprints.Previous(dc.now); ok {
+			return sp, nil
+		}
+		return calendar.Sprint{}, fmt.Errorf("no sprint before the current one in the sprint list")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return calendar.Sprint{}, fmt.Errorf("must be current, previous or a sprint number")
+	}
+	sp, ok := sprints.Number(n, dc.now.Location())
+	if !ok {
+		return calendar.Sprint{}, fmt.Errorf("no sprint %d: the sprint list has %d", n, len(sprints.List))
+	}
+	return sp, nil
+}
+
 // parseDate tries YYYY-MM-DD first, then falls back to natural language parsing
 // via go-naturaldate. The ref time is used as the reference point for relative
 // expressions (e.g. "2 weeks ago" is relative to ref).
@@ -0,0 +1,74 @@
+package bitbucket
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"worklog/internal/provider"
+	"worklog/internal/report"
+)
+
+const cloudBaseURL = "https://api.bitbucket.org/2.0"
+
+func init() {
+	provider.Register("bitbucket", func() provider.Provider { return &Provider{} })
+}
+
+// Provider reports activity for the Bitbucket user owning BITBUCKET_TOKEN.
+// It talks to Bitbucket Cloud unless BITBUCKET_URL points at a self-hosted
+// Bitbucket Server or Data Center instance.
+type Provider struct {
+	username string
+	token    string
+	baseURL  string
+	filter   report.RepoFilter
+}
+
+func (p *Provider) Name() string { return "bitbucket" }
+
+func (p *Provider) Configure(lookup func(string) string) (bool, error) {
+	p.username = lookup("USERNAME")
+	p.token = lookup("TOKEN")
+	p.baseURL = lookup("URL")
+	return p.token != "", nil
+}
+
+func (p *Provider) Identity() string {
+	return p.baseURL + "\x00" + p.username + "\x00" + p.token
+}
+
+func (p *Provider) SetRepoFilter(f report.RepoFilter) { p.filter = f }
+
+func (p *Provider) FetchEvents(ctx context.Context, since, until time.Time) ([]report.Event, error) {
+	return FetchEvents(ctx, p.username, p.token, p.baseURL, p.filter, since, until)
+}
+
+// FetchEvents authenticates with username and token as an app password when
+// username is set, and with token as a bearer access token otherwise.
+func FetchEvents(ctx context.Context, username, token, baseURL string, filter report.RepoFilter, since, until time.Time) ([]report.Event, error) {
+	if isCloud(baseURL) {
+		return fetchCloud(ctx, newClient(cloudBaseURL, username, token), filter, since, until)
+	}
+	return fetchServer(ctx, newClient(strings.TrimRight(baseURL, "/"), username, token), filter, since, until)
+}
+
+// isCloud reports whether baseURL refers to Bitbucket Cloud rather than a
+// self-hosted instance.
+func isCloud(baseURL string) bool {
+	if baseURL == "" {
+		return true
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(u.Hostname(), "api.")
+	return host == "bitbucket.org"
+}
+
+// inRange reports whether t falls within [since, until].
+func inRange(t, since, until time.Time) bool {
+	return !t.Before(since) && !t.After(until)
+}
@@ -0,0 +1,231 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"worklog/internal/report"
+	"worklog/internal/testutil"
+)
+
+// fixtureServer answers GET requests with the responses in a testdata file,
+// keyed by path and the query parameters that select a page or a listing.
+// "{{server}}" in the file is replaced with the server's URL, for the
+// absolute "next" links of Bitbucket Cloud. Requests without a fixture get
+// 404, and requests that authorized rejects get 401.
+type fixtureServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requested []string
+}
+
+// fixtureParams are the query parameters that are part of a fixture's key.
+var fixtureParams = []string{"page", "role", "start", "state"}
+
+func newFixtureServer(t *testing.T, file string, authorized func(*http.Request) bool) *fixtureServer {
+	t.Helper()
+	s := &fixtureServer{}
+	var fixtures map[string]json.RawMessage
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path
+		q := make(url.Values)
+		for _, p := range fixtureParams {
+			if v, ok := r.URL.Query()[p]; ok {
+				q[p] = v
+			}
+		}
+		if len(q) > 0 {
+			key += "?" + q.Encode()
+		}
+		s.mu.Lock()
+		s.requested = append(s.requested, key)
+		s.mu.Unlock()
+
+		if !authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, ok := fixtures[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var text string
+		if json.Unmarshal(body, &text) == nil {
+			fmt.Fprint(w, text)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	fixtures = testutil.Fixtures(t, file, s.URL)
+	return s
+}
+
+func (s *fixtureServer) wasRequested(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Contains(s.requested, key)
+}
+
+func basicAuth(username, password string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		u, p, ok := r.BasicAuth()
+		return ok && u == username && p == password
+	}
+}
+
+func bearerAuth(token string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer "+token
+	}
+}
+
+func TestFetchCloud(t *testing.T) {
+	s := newFixtureServer(t, "testdata/cloud.json", basicAuth("jdoe", "app-password"))
+	c := newClient(s.URL, "jdoe", "app-password")
+	events, err := fetchCloud(context.Background(), c, report.RepoFilter{}, testutil.Since, testutil.Until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CheckEvents(t, events, []string{
+		`Pull Requests / Merge Requests | opened | #1 Add caching | acme/api | https://bitbucket.org/acme/api/pull-requests/1 | bitbucket | 2026-10-13T09:00:00Z | branch "caching" commits [c1 c2]`,
+		`Pull Requests / Merge Requests | merged | #1 Add caching | acme/api | https://bitbucket.org/acme/api/pull-requests/1 | bitbucket | 2026-10-14T15:30:00Z | branch "caching" commits [c1 c2]`,
+		`Code Reviews | approved | #2 Fix login | acme/api | https://bitbucket.org/acme/api/pull-requests/2 | bitbucket | 2026-10-15T11:00:00Z`,
+		`Commits | pushed | Cache responses | acme/api | https://bitbucket.org/acme/api/commits/c2 | bitbucket | 2026-10-13T10:00:00Z | sha c2`,
+		`CI Pipeline Failures | failed | pipeline #7 on caching | acme/api | https://bitbucket.org/acme/api/pipelines/results/7 | bitbucket | 2026-10-14T12:00:00Z`,
+		`Pending Reviews | awaiting your review | #3 Bump dependencies | acme/api | https://bitbucket.org/acme/api/pull-requests/3 | bitbucket | 2026-10-09T12:00:00Z`,
+	})
+
+	// The second repository page is followed, and paging stops at the
+	// first entry before the range.
+	for _, key := range []string{"/repositories/acme?page=2", "/repositories/acme/web/commits"} {
+		if !s.wasRequested(key) {
+			t.Errorf("%s was not requested", key)
+		}
+	}
+	for _, key := range []string{"/repositories/acme/api/commits?page=3", "/repositories/acme/api/pullrequests/activity?page=3"} {
+		if s.wasRequested(key) {
+			t.Errorf("%s was requested past the start of the range", key)
+		}
+	}
+}
+
+func TestFetchCloudFilter(t *testing.T) {
+	s := newFixtureServer(t, "testdata/cloud.json", bearerAuth("token"))
+	c := newClient(s.URL, "", "token")
+	filter := report.RepoFilter{ExcludeOrgs: []string{"acme"}}
+	events, err := fetchCloud(context.Background(), c, filter, testutil.Since, testutil.Until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("got events from an excluded workspace:\n%s", strings.Join(testutil.Summarize(events), "\n"))
+	}
+	if s.wasRequested("/repositories/acme") {
+		t.Error("an excluded workspace was listed")
+	}
+}
+
+func TestFetchServer(t *testing.T) {
+	s := newFixtureServer(t, "testdata/server.json", bearerAuth("token"))
+	c := newClient(s.URL, "", "token")
+	events, err := fetchServer(context.Background(), c, report.RepoFilter{}, testutil.Since, testutil.Until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CheckEvents(t, events, []string{
+		`Pull Requests / Merge Requests | opened | #5 Add retries | PROJ/api | https://bb.example.com/projects/PROJ/repos/api/pull-requests/5 | bitbucket | 2026-10-13T09:00:00Z | branch "retries" commits [s1 s2]`,
+		`Code Reviews | approved | #5 Add retries | PROJ/api | https://bb.example.com/projects/PROJ/repos/api/pull-requests/5 | bitbucket | 2026-10-16T18:00:00Z`,
+		`Pull Requests / Merge Requests | merged | #4 Fix typo | PROJ/web | https://bb.example.com/projects/PROJ/repos/web/pull-requests/4 | bitbucket | 2026-10-14T15:30:00Z | branch "" commits [s4]`,
+		`Commits | pushed | Retry on 503 | PROJ/api | https://bb.example.com/projects/PROJ/repos/api/commits/s2 | bitbucket | 2026-10-13T10:00:00Z | sha s2`,
+		`Commits | pushed | Add --verbose | PROJ/cli | https://bb.example.com/projects/PROJ/repos/cli/commits/s9 | bitbucket | 2026-10-15T11:00:00Z | sha s9`,
+		`Pending Reviews | awaiting your review | #9 Update docs | PROJ/api | https://bb.example.com/projects/PROJ/repos/api/pull-requests/9 | bitbucket | 2026-10-09T12:00:00Z`,
+	})
+
+	// Pull requests are paged until one was last updated before the range,
+	// and so are commits.
+	if !s.wasRequested("/rest/api/1.0/projects/PROJ/repos/api/commits?start=2") {
+		t.Error("the second page of commits was not requested")
+	}
+	for _, key := range []string{
+		"/rest/api/1.0/dashboard/pull-requests?start=3&state=ALL",
+		"/rest/api/1.0/projects/PROJ/repos/api/pull-requests/2/activities",
+		"/rest/api/1.0/projects/PROJ/repos/api/commits?start=3",
+	} {
+		if s.wasRequested(key) {
+			t.Errorf("%s was requested past the start of the range", key)
+		}
+	}
+}
+
+func TestFetchServerFilter(t *testing.T) {
+	s := newFixtureServer(t, "testdata/server.json", basicAuth("jdoe", "token"))
+	c := newClient(s.URL, "jdoe", "token")
+	filter := report.RepoFilter{IncludeRepos: []string{"PROJ/web"}}
+	events, err := fetchServer(context.Background(), c, filter, testutil.Since, testutil.Until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pending reviews are not filtered here; the filter is applied to all
+	// events afterwards.
+	testutil.CheckEvents(t, events, []string{
+		`Pull Requests / Merge Requests | merged | #4 Fix typo | PROJ/web | https://bb.example.com/projects/PROJ/repos/web/pull-requests/4 | bitbucket | 2026-10-14T15:30:00Z | branch "" commits [s4]`,
+		`Pending Reviews | awaiting your review | #9 Update docs | PROJ/api | https://bb.example.com/projects/PROJ/repos/api/pull-requests/9 | bitbucket | 2026-10-09T12:00:00Z`,
+	})
+	if s.wasRequested("/rest/api/1.0/projects/PROJ/repos/api/pull-requests/5/activities") {
+		t.Error("the activity of an excluded repository was read")
+	}
+}
+
+func TestAuthErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		fetch func(context.Context, *client, report.RepoFilter, time.Time, time.Time) ([]report.Event, error)
+		err   string
+	}{
+		{"cloud", "testdata/cloud.json", fetchCloud, "getting user: GET /user: 401 Unauthorized"},
+		{"server", "testdata/server.json", fetchServer, "getting user: GET /plugins/servlet/applinks/whoami: 401 Unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFixtureServer(t, tt.file, basicAuth("jdoe", "app-password"))
+			for _, c := range []*client{
+				newClient(s.URL, "jdoe", "wrong"),
+				newClient(s.URL, "", "app-password"),
+			} {
+				_, err := tt.fetch(context.Background(), c, report.RepoFilter{}, testutil.Since, testutil.Until)
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("got error %v, want %q", err, tt.err)
+				}
+			}
+		})
+	}
+}
+
+func TestIsCloud(t *testing.T) {
+	tests := map[string]bool{
+		"":                                true,
+		"https://bitbucket.org":           true,
+		"https://api.bitbucket.org/2.0":   true,
+		"https://bitbucket.example.com":   false,
+		"https://bitbucket.org.evil.test": false,
+	}
+	for baseURL, want := range tests {
+		if got := isCloud(baseURL); got != want {
+			t.Errorf("isCloud(%q) = %t, want %t", baseURL, got, want)
+		}
+	}
+}
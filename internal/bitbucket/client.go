@@ -0,0 +1,89 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// client is a minimal JSON client shared by the Cloud (2.0) and Server
+// (REST 1.0) APIs, which differ in paths and pagination but not in transport.
+type client struct {
+	http     *http.Client
+	baseURL  string
+	username string
+	token    string
+}
+
+func newClient(baseURL, username, token string) *client {
+	return &client{
+		http:     http.DefaultClient,
+		baseURL:  baseURL,
+		username: username,
+		token:    token,
+	}
+}
+
+// get issues a GET request for path, relative to the base URL unless it is
+// already absolute (as with Cloud "next" links), and decodes the JSON
+// response into v.
+func (c *client) get(ctx context.Context, path string, query map[string]string, v any) error {
+	body, err := c.do(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(v)
+}
+
+// getText is like get but returns the raw response body as a string.
+func (c *client) getText(ctx context.Context, path string) (string, error) {
+	body, err := c.do(ctx, path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	b, err := io.ReadAll(body)
+	return strings.TrimSpace(string(b)), err
+}
+
+func (c *client) do(ctx context.Context, path string, query map[string]string) (io.ReadCloser, error) {
+	raw := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		raw = c.baseURL + path
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	for k, val := range query {
+		q.Set(k, val)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", u.Path, resp.Status)
+	}
+	return resp.Body, nil
+}
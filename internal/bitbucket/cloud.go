@@ -0,0 +1,398 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"worklog/internal/report"
+)
+
+type cloudUser struct {
+	UUID string `json:"uuid"`
+}
+
+type cloudLink struct {
+	Href string `json:"href"`
+}
+
+type cloudRepo struct {
+	FullName string `json:"full_name"`
+	Links    struct {
+		HTML cloudLink `json:"html"`
+	} `json:"links"`
+}
+
+type cloudPullRequest struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	CreatedOn time.Time `json:"created_on"`
+	Links     struct {
+		HTML cloudLink `json:"html"`
+	} `json:"links"`
+	Source struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+		Repository *struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	} `json:"source"`
+}
+
+// cloudActivity is an entry of the pull request activity log. Exactly one of
+// Approval, ChangesRequested, Comment and Update is set.
+type cloudActivity struct {
+	PullRequest cloudPullRequest `json:"pull_request"`
+	Approval    *struct {
+		Date time.Time `json:"date"`
+		User cloudUser `json:"user"`
+	} `json:"approval"`
+	ChangesRequested *struct {
+		Date time.Time `json:"date"`
+		User cloudUser `json:"user"`
+	} `json:"changes_requested"`
+	Comment *struct {
+		CreatedOn time.Time `json:"created_on"`
+		User      cloudUser `json:"user"`
+		Links     struct {
+			HTML cloudLink `json:"html"`
+		} `json:"links"`
+	} `json:"comment"`
+	Update *struct {
+		State  string    `json:"state"`
+		Date   time.Time `json:"date"`
+		Author cloudUser `json:"author"`
+	} `json:"update"`
+}
+
+type cloudCommit struct {
+	Hash    string    `json:"hash"`
+	Message string    `json:"message"`
+	Date    time.Time `json:"date"`
+	Author  struct {
+		User *cloudUser `json:"user"`
+	} `json:"author"`
+	Links struct {
+		HTML cloudLink `json:"html"`
+	} `json:"links"`
+}
+
+type cloudPipeline struct {
+	BuildNumber int64     `json:"build_number"`
+	CreatedOn   time.Time `json:"created_on"`
+	Creator     cloudUser `json:"creator"`
+	State       struct {
+		Result *struct {
+			Name string `json:"name"`
+		} `json:"result"`
+	} `json:"state"`
+	Target struct {
+		RefName string `json:"ref_name"`
+	} `json:"target"`
+}
+
+// cloudPage is the envelope of paginated Bitbucket Cloud responses.
+type cloudPage[T any] struct {
+	Values []T    `json:"values"`
+	Next   string `json:"next"`
+}
+
+// fetchCloud collects activity from Bitbucket Cloud. Cloud has no per-user
+// activity feed, so it walks the repositories updated since the start of the
+// range in every workspace the user belongs to that passes filter.
+func fetchCloud(ctx context.Context, c *client, filter report.RepoFilter, since, until time.Time) ([]report.Event, error) {
+	var me cloudUser
+	if err := c.get(ctx, "/user", nil, &me); err != nil {
+		return nil, fmt.Errorf("getting user: %w", err)
+	}
+
+	repos, err := cloudRepos(ctx, c, filter, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []report.Event
+	for _, repo := range repos {
+		events = append(events, cloudPullRequestEvents(ctx, c, me, repo, since, until)...)
+		events = append(events, cloudCommitEvents(ctx, c, me, repo, since, until)...)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ciEvents, err := fetchCloudPipelineFailures(ctx, c, me, repos, since, until)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: bitbucket pipeline failures: %v\n", err)
+			return
+		}
+		mu.Lock()
+		events = append(events, ciEvents...)
+		mu.Unlock()
+	}()
+
+	go func() {
+		defer wg.Done()
+		prEvents, err := fetchCloudPendingReviews(ctx, c, me, repos)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: bitbucket pending reviews: %v\n", err)
+			return
+		}
+		mu.Lock()
+		events = append(events, prEvents...)
+		mu.Unlock()
+	}()
+
+	wg.Wait()
+	return events, nil
+}
+
+func cloudRepos(ctx context.Context, c *client, filter report.RepoFilter, since time.Time) ([]cloudRepo, error) {
+	var workspaces cloudPage[struct {
+		Workspace struct {
+			Slug string `json:"slug"`
+		} `json:"workspace"`
+	}]
+	if err := c.get(ctx, "/user/permissions/workspaces", map[string]string{"pagelen": "100"}, &workspaces); err != nil {
+		return nil, fmt.Errorf("listing workspaces: %w", err)
+	}
+
+	var repos []cloudRepo
+	for _, ws := range workspaces.Values {
+		if !filter.MatchOrg(ws.Workspace.Slug) {
+			continue
+		}
+		next := "/repositories/" + ws.Workspace.Slug
+		query := map[string]string{
+			"q":       fmt.Sprintf("updated_on >= %s", since.UTC().Format(time.RFC3339)),
+			"pagelen": "100",
+		}
+		for page := 0; next != "" && page < 5; page++ {
+			var result cloudPage[cloudRepo]
+			if err := c.get(ctx, next, query, &result); err != nil {
+				return nil, fmt.Errorf("listing repositories in %s: %w", ws.Workspace.Slug, err)
+			}
+			for _, repo := range result.Values {
+				if filter.Match(repo.FullName) {
+					repos = append(repos, repo)
+				}
+			}
+			next, query = result.Next, nil
+		}
+	}
+	return repos, nil
+}
+
+// cloudPullRequestEvents reports the pull requests the user opened and their
+// approvals, change requests, comments, merges and declines in repo.
+func cloudPullRequestEvents(ctx context.Context, c *client, me cloudUser, repo cloudRepo, since, until time.Time) []report.Event {
+	var events []report.Event
+	event := func(cat report.EventCategory, action string, pr cloudPullRequest, url string, at time.Time) {
+		if url == "" {
+			url = pr.Links.HTML.Href
+		}
+		events = append(events, report.Event{
+			Category:  cat,
+			Action:    action,
+			Title:     fmt.Sprintf("#%d %s", pr.ID, pr.Title),
+			URL:       url,
+			Repo:      repo.FullName,
+			Source:    "bitbucket",
+			CreatedAt: at,
+		})
+	}
+
+	var authored cloudPage[cloudPullRequest]
+	query := map[string]string{
+		"q":       fmt.Sprintf(`author.uuid = "%s" AND created_on >= %s`, me.UUID, since.UTC().Format(time.RFC3339)),
+		"pagelen": "50",
+	}
+	// The endpoint only lists open pull requests unless told otherwise, and
+	// repeated state parameters are not expressible in the query map.
+	path := fmt.Sprintf("/repositories/%s/pullrequests?state=OPEN&state=MERGED&state=DECLINED&state=SUPERSEDED", repo.FullName)
+	if err := c.get(ctx, path, query, &authored); err == nil {
+		for _, pr := range authored.Values {
+			if inRange(pr.CreatedOn, since, until) {
+				event(report.CategoryPR, "opened", pr, "", pr.CreatedOn)
+			}
+		}
+	}
+
+	next := fmt.Sprintf("/repositories/%s/pullrequests/activity", repo.FullName)
+	query = map[string]string{"pagelen": "50"}
+	for page := 0; next != "" && page < 10; page++ {
+		var result cloudPage[cloudActivity]
+		if err := c.get(ctx, next, query, &result); err != nil {
+			break
+		}
+		done := false
+		for _, a := range result.Values {
+			var at time.Time
+			switch {
+			case a.Approval != nil:
+				at = a.Approval.Date
+				if a.Approval.User.UUID == me.UUID && inRange(at, since, until) {
+					event(report.CategoryReview, "approved", a.PullRequest, "", at)
+				}
+			case a.ChangesRequested != nil:
+				at = a.ChangesRequested.Date
+				if a.ChangesRequested.User.UUID == me.UUID && inRange(at, since, until) {
+					event(report.CategoryReview, "changes requested", a.PullRequest, "", at)
+				}
+			case a.Comment != nil:
+				at = a.Comment.CreatedOn
+				if a.Comment.User.UUID == me.UUID && inRange(at, since, until) {
+					event(report.CategoryReviewComment, "commented", a.PullRequest, a.Comment.Links.HTML.Href, at)
+				}
+			case a.Update != nil:
+				at = a.Update.Date
+				if a.Update.Author.UUID == me.UUID && inRange(at, since, until) {
+					switch a.Update.State {
+					case "MERGED":
+						event(report.CategoryPR, "merged", a.PullRequest, "", at)
+					case "DECLINED":
+						event(report.CategoryPR, "declined", a.PullRequest, "", at)
+					}
+				}
+			}
+			if !at.IsZero() && at.Before(since) {
+				done = true
+			}
+		}
+		if done {
+			break
+		}
+		next, query = result.Next, nil
+	}
+	// Like the activity pages, pull requests that cannot be read are skipped.
+	_ = report.LinkPullRequests(events, func(e report.Event) (report.PullRequestCommits, error) {
+		return readCloudPullRequest(ctx, c, repo, e)
+	})
+	return events
+}
+
+// readCloudPullRequest reads the source branch and commits of the pull
+// request of e in repo for report.LinkPullRequests. Activity entries carry a
+// partial pull request, so it is read in full.
+func readCloudPullRequest(ctx context.Context, c *client, repo cloudRepo, e report.Event) (report.PullRequestCommits, error) {
+	var pr report.PullRequestCommits
+	var id int64
+	if _, err := fmt.Sscanf(e.Title, "#%d", &id); err != nil {
+		return pr, err
+	}
+	path := fmt.Sprintf("/repositories/%s/pullrequests/%d", repo.FullName, id)
+	var full cloudPullRequest
+	if err := c.get(ctx, path, nil, &full); err != nil {
+		return pr, err
+	}
+	var commits cloudPage[cloudCommit]
+	if err := c.get(ctx, path+"/commits", map[string]string{"pagelen": "100"}, &commits); err != nil {
+		return pr, err
+	}
+	if full.Source.Repository != nil && full.Source.Repository.FullName == repo.FullName {
+		pr.Branch = full.Source.Branch.Name
+	}
+	for _, commit := range commits.Values {
+		pr.SHAs = append(pr.SHAs, commit.Hash)
+	}
+	return pr, nil
+}
+
+// cloudCommitEvents reports commits in repo, across all branches, whose
+// author is linked to the user's account.
+func cloudCommitEvents(ctx context.Context, c *client, me cloudUser, repo cloudRepo, since, until time.Time) []report.Event {
+	var events []report.Event
+	next := fmt.Sprintf("/repositories/%s/commits", repo.FullName)
+	query := map[string]string{"pagelen": "100"}
+	for page := 0; next != "" && page < 5; page++ {
+		var result cloudPage[cloudCommit]
+		if err := c.get(ctx, next, query, &result); err != nil {
+			break
+		}
+		done := false
+		for _, commit := range result.Values {
+			if commit.Date.Before(since) {
+				done = true
+				continue
+			}
+			if commit.Date.After(until) || commit.Author.User == nil || commit.Author.User.UUID != me.UUID {
+				continue
+			}
+			events = append(events, report.Event{
+				Category:  report.CategoryCommit,
+				Action:    "pushed",
+				Title:     report.FirstLine(commit.Message),
+				URL:       commit.Links.HTML.Href,
+				Repo:      repo.FullName,
+				Source:    "bitbucket",
+				SHA:       commit.Hash,
+				CreatedAt: commit.Date,
+			})
+		}
+		if done {
+			break
+		}
+		next, query = result.Next, nil
+	}
+	return events
+}
+
+func fetchCloudPipelineFailures(ctx context.Context, c *client, me cloudUser, repos []cloudRepo, since, until time.Time) ([]report.Event, error) {
+	var events []report.Event
+	for _, repo := range repos {
+		var result cloudPage[cloudPipeline]
+		query := map[string]string{"sort": "-created_on", "pagelen": "100"}
+		if err := c.get(ctx, fmt.Sprintf("/repositories/%s/pipelines/", repo.FullName), query, &result); err != nil {
+			continue
+		}
+		for _, p := range result.Values {
+			if p.Creator.UUID != me.UUID || p.State.Result == nil || p.State.Result.Name != "FAILED" {
+				continue
+			}
+			if !inRange(p.CreatedOn, since, until) {
+				continue
+			}
+			events = append(events, report.Event{
+				Category:  report.CategoryPipeline,
+				Action:    "failed",
+				Title:     fmt.Sprintf("pipeline #%d on %s", p.BuildNumber, p.Target.RefName),
+				URL:       fmt.Sprintf("%s/pipelines/results/%d", repo.Links.HTML.Href, p.BuildNumber),
+				Repo:      repo.FullName,
+				Source:    "bitbucket",
+				CreatedAt: p.CreatedOn,
+			})
+		}
+	}
+	return events, nil
+}
+
+func fetchCloudPendingReviews(ctx context.Context, c *client, me cloudUser, repos []cloudRepo) ([]report.Event, error) {
+	var events []report.Event
+	for _, repo := range repos {
+		var result cloudPage[cloudPullRequest]
+		query := map[string]string{
+			"q":       fmt.Sprintf(`state = "OPEN" AND reviewers.uuid = "%s"`, me.UUID),
+			"pagelen": "50",
+		}
+		if err := c.get(ctx, fmt.Sprintf("/repositories/%s/pullrequests", repo.FullName), query, &result); err != nil {
+			continue
+		}
+		for _, pr := range result.Values {
+			events = append(events, report.Event{
+				Category:  report.CategoryPendingReview,
+				Action:    "awaiting your review",
+				Title:     fmt.Sprintf("#%d %s", pr.ID, pr.Title),
+				URL:       pr.Links.HTML.Href,
+				Repo:      repo.FullName,
+				Source:    "bitbucket",
+				CreatedAt: pr.CreatedOn,
+			})
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,297 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"worklog/internal/report"
+)
+
+type serverUser struct {
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	EmailAddress string `json:"emailAddress"`
+}
+
+type serverLinks struct {
+	Self []struct {
+		Href string `json:"href"`
+	} `json:"self"`
+}
+
+func (l serverLinks) href() string {
+	if len(l.Self) == 0 {
+		return ""
+	}
+	return l.Self[0].Href
+}
+
+type serverRepo struct {
+	Slug    string `json:"slug"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+	Links serverLinks `json:"links"`
+}
+
+// fullName returns the repository as "PROJECT/slug", the Server counterpart
+// of a Cloud "workspace/slug" full name.
+func (r serverRepo) fullName() string {
+	return r.Project.Key + "/" + r.Slug
+}
+
+func (r serverRepo) apiPath() string {
+	return fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s", r.Project.Key, r.Slug)
+}
+
+type serverPullRequest struct {
+	ID          int64       `json:"id"`
+	Title       string      `json:"title"`
+	CreatedDate int64       `json:"createdDate"`
+	UpdatedDate int64       `json:"updatedDate"`
+	Links       serverLinks `json:"links"`
+	FromRef     struct {
+		DisplayID  string     `json:"displayId"`
+		Repository serverRepo `json:"repository"`
+	} `json:"fromRef"`
+	ToRef struct {
+		Repository serverRepo `json:"repository"`
+	} `json:"toRef"`
+}
+
+type serverActivity struct {
+	Action      string     `json:"action"`
+	CreatedDate int64      `json:"createdDate"`
+	User        serverUser `json:"user"`
+}
+
+type serverCommit struct {
+	ID              string     `json:"id"`
+	Message         string     `json:"message"`
+	AuthorTimestamp int64      `json:"authorTimestamp"`
+	Author          serverUser `json:"author"`
+}
+
+// serverPage is the envelope of paginated Bitbucket Server responses.
+type serverPage[T any] struct {
+	Values        []T  `json:"values"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+// millis converts a Bitbucket Server epoch-milliseconds timestamp.
+func millis(ms int64) time.Time {
+	return time.UnixMilli(ms)
+}
+
+// fetchServer collects activity from Bitbucket Server or Data Center. Pull
+// request activity comes from the dashboard, and commits from the repositories
+// those pull requests and the user's recently viewed repositories belong to.
+// Server has no built-in CI, so pipeline failures are not reported.
+func fetchServer(ctx context.Context, c *client, filter report.RepoFilter, since, until time.Time) ([]report.Event, error) {
+	username, err := c.getText(ctx, "/plugins/servlet/applinks/whoami")
+	if err != nil {
+		return nil, fmt.Errorf("getting user: %w", err)
+	}
+	var me serverUser
+	if err := c.get(ctx, "/rest/api/1.0/users/"+username, nil, &me); err != nil {
+		return nil, fmt.Errorf("getting user: %w", err)
+	}
+
+	repos := make(map[string]serverRepo)
+	var events []report.Event
+
+	start := 0
+	for page := 0; page < 10; page++ {
+		var result serverPage[serverPullRequest]
+		query := map[string]string{
+			"state": "ALL",
+			"order": "NEWEST",
+			"limit": "50",
+			"start": strconv.Itoa(start),
+		}
+		if err := c.get(ctx, "/rest/api/1.0/dashboard/pull-requests", query, &result); err != nil {
+			return nil, err
+		}
+		done := false
+		for _, pr := range result.Values {
+			if millis(pr.UpdatedDate).Before(since) {
+				done = true
+				break
+			}
+			repo := pr.ToRef.Repository
+			if !filter.Match(repo.fullName()) {
+				continue
+			}
+			repos[repo.fullName()] = repo
+			events = append(events, serverPullRequestEvents(ctx, c, me, pr, since, until)...)
+		}
+		if done || result.IsLastPage {
+			break
+		}
+		start = result.NextPageStart
+	}
+
+	var recent serverPage[serverRepo]
+	if err := c.get(ctx, "/rest/api/1.0/profile/recent/repos", map[string]string{"limit": "25"}, &recent); err == nil {
+		for _, repo := range recent.Values {
+			if filter.Match(repo.fullName()) {
+				repos[repo.fullName()] = repo
+			}
+		}
+	}
+	for _, repo := range repos {
+		events = append(events, serverCommitEvents(ctx, c, me, repo, since, until)...)
+	}
+
+	prEvents, err := fetchServerPendingReviews(ctx, c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: bitbucket pending reviews: %v\n", err)
+	} else {
+		events = append(events, prEvents...)
+	}
+
+	return events, nil
+}
+
+// serverPullRequestEvents maps the user's own actions in pr's activity log
+// onto report events.
+func serverPullRequestEvents(ctx context.Context, c *client, me serverUser, pr serverPullRequest, since, until time.Time) []report.Event {
+	var result serverPage[serverActivity]
+	path := fmt.Sprintf("%s/pull-requests/%d/activities", pr.ToRef.Repository.apiPath(), pr.ID)
+	if err := c.get(ctx, path, map[string]string{"limit": "100"}, &result); err != nil {
+		return nil
+	}
+
+	var events []report.Event
+	for _, a := range result.Values {
+		at := millis(a.CreatedDate)
+		if a.User.Slug != me.Slug || !inRange(at, since, until) {
+			continue
+		}
+		var cat report.EventCategory
+		var action string
+		switch a.Action {
+		case "OPENED":
+			cat, action = report.CategoryPR, "opened"
+		case "MERGED":
+			cat, action = report.CategoryPR, "merged"
+		case "DECLINED":
+			cat, action = report.CategoryPR, "declined"
+		case "REOPENED":
+			cat, action = report.CategoryPR, "reopened"
+		case "APPROVED":
+			cat, action = report.CategoryReview, "approved"
+		case "REVIEWED":
+			cat, action = report.CategoryReview, "changes requested"
+		case "COMMENTED":
+			cat, action = report.CategoryReviewComment, "commented"
+		default:
+			continue
+		}
+		events = append(events, report.Event{
+			Category:  cat,
+			Action:    action,
+			Title:     fmt.Sprintf("#%d %s", pr.ID, pr.Title),
+			URL:       pr.Links.href(),
+			Repo:      pr.ToRef.Repository.fullName(),
+			Source:    "bitbucket",
+			CreatedAt: at,
+		})
+	}
+	// Like the activity, commits that cannot be read are skipped.
+	_ = report.LinkPullRequests(events, func(e report.Event) (report.PullRequestCommits, error) {
+		return readServerPullRequest(ctx, c, pr, e)
+	})
+	return events
+}
+
+// readServerPullRequest reads the commits of pr for report.LinkPullRequests.
+func readServerPullRequest(ctx context.Context, c *client, pr serverPullRequest, e report.Event) (report.PullRequestCommits, error) {
+	var linked report.PullRequestCommits
+	if pr.FromRef.Repository.fullName() == e.Repo {
+		linked.Branch = pr.FromRef.DisplayID
+	}
+	var result serverPage[serverCommit]
+	path := fmt.Sprintf("%s/pull-requests/%d/commits", pr.ToRef.Repository.apiPath(), pr.ID)
+	if err := c.get(ctx, path, map[string]string{"limit": "100"}, &result); err != nil {
+		return linked, err
+	}
+	for _, commit := range result.Values {
+		linked.SHAs = append(linked.SHAs, commit.ID)
+	}
+	return linked, nil
+}
+
+// serverCommitEvents reports commits on repo's default branch authored by the
+// user, matched by email address.
+func serverCommitEvents(ctx context.Context, c *client, me serverUser, repo serverRepo, since, until time.Time) []report.Event {
+	webURL := strings.TrimSuffix(repo.Links.href(), "/browse")
+
+	var events []report.Event
+	start := 0
+	for page := 0; page < 5; page++ {
+		var result serverPage[serverCommit]
+		query := map[string]string{"limit": "100", "start": strconv.Itoa(start)}
+		if err := c.get(ctx, repo.apiPath()+"/commits", query, &result); err != nil {
+			break
+		}
+		done := false
+		for _, commit := range result.Values {
+			at := millis(commit.AuthorTimestamp)
+			if at.Before(since) {
+				done = true
+				break
+			}
+			if at.After(until) || !strings.EqualFold(commit.Author.EmailAddress, me.EmailAddress) {
+				continue
+			}
+			events = append(events, report.Event{
+				Category:  report.CategoryCommit,
+				Action:    "pushed",
+				Title:     report.FirstLine(commit.Message),
+				URL:       webURL + "/commits/" + commit.ID,
+				Repo:      repo.fullName(),
+				Source:    "bitbucket",
+				SHA:       commit.ID,
+				CreatedAt: at,
+			})
+		}
+		if done || result.IsLastPage {
+			break
+		}
+		start = result.NextPageStart
+	}
+	return events
+}
+
+func fetchServerPendingReviews(ctx context.Context, c *client) ([]report.Event, error) {
+	var result serverPage[serverPullRequest]
+	query := map[string]string{
+		"role":              "REVIEWER",
+		"state":             "OPEN",
+		"participantStatus": "UNAPPROVED",
+		"limit":             "50",
+	}
+	if err := c.get(ctx, "/rest/api/1.0/dashboard/pull-requests", query, &result); err != nil {
+		return nil, err
+	}
+
+	var events []report.Event
+	for _, pr := range result.Values {
+		events = append(events, report.Event{
+			Category:  report.CategoryPendingReview,
+			Action:    "awaiting your review",
+			Title:     fmt.Sprintf("#%d %s", pr.ID, pr.Title),
+			URL:       pr.Links.href(),
+			Repo:      pr.ToRef.Repository.fullName(),
+			Source:    "bitbucket",
+			CreatedAt: millis(pr.CreatedDate),
+		})
+	}
+	return events, nil
+}
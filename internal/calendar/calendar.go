@@ -0,0 +1,86 @@
+// Package calendar knows which days are working days and where sprints
+// start, so that report ranges can be given as "the last working day" or
+// "this sprint" rather than as dates.
+package calendar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+// DefaultWorkWeek is the work week when none is configured.
+var DefaultWorkWeek = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Calendar describes the user's working days and sprints. Dates are civil
+// dates, compared in the location of the times passed to its methods.
+type Calendar struct {
+	WorkWeek []time.Weekday
+	Holidays map[string]string // name by "2006-01-02" date
+	Sprints  *Sprints
+}
+
+// ParseWeekday parses a day name such as "mon" or "Monday".
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(s)
+	if len(s) >= 3 {
+		if d, ok := weekdayNames[s[:3]]; ok && strings.HasPrefix(strings.ToLower(d.String()), s) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q: must be one of mon, tue, wed, thu, fri, sat, sun", s)
+}
+
+// ParseWorkWeek parses a list of day names. An empty list is the default
+// Monday to Friday week.
+func ParseWorkWeek(names []string) ([]time.Weekday, error) {
+	if len(names) == 0 {
+		return DefaultWorkWeek, nil
+	}
+	days := make([]time.Weekday, 0, len(names))
+	for _, name := range names {
+		d, err := ParseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// IsWorkday reports whether the day of t is in the work week and not a
+// holiday.
+func (c Calendar) IsWorkday(t time.Time) bool {
+	if _, ok := c.Holidays[t.Format(dateFormat)]; ok {
+		return false
+	}
+	week := c.WorkWeek
+	if len(week) == 0 {
+		week = DefaultWorkWeek
+	}
+	return slices.Contains(week, t.Weekday())
+}
+
+// PrevWorkday returns the start of the last working day before the day of
+// t. It gives up after a year without one and returns the day before.
+func (c Calendar) PrevWorkday(t time.Time) time.Time {
+	day := startOfDay(t)
+	for i := 1; i <= 366; i++ {
+		if d := day.AddDate(0, 0, -i); c.IsWorkday(d) {
+			return d
+		}
+	}
+	return day.AddDate(0, 0, -1)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,125 @@
+package calendar
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadHolidays reads a holiday list from an iCalendar file (.ics), such as
+// the public holiday calendars offered by most calendar apps, or from a YAML
+// file (.yaml, .yml) that maps dates to names:
+//
+//	2026-12-25: Christmas Day
+//	2026-12-26: Boxing Day
+//
+// It returns the holiday names by "2006-01-02" date.
+func LoadHolidays(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics":
+		return parseICal(data)
+	case ".yaml", ".yml":
+		return parseYAML(data)
+	default:
+		return nil, fmt.Errorf("%s: holidays must be an .ics or .yaml file", path)
+	}
+}
+
+func parseYAML(data []byte) (map[string]string, error) {
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	holidays := make(map[string]string, len(raw))
+	for date, name := range raw {
+		if _, err := time.Parse(dateFormat, date); err != nil {
+			return nil, fmt.Errorf("invalid date %q: must be YYYY-MM-DD", date)
+		}
+		holidays[date] = name
+	}
+	return holidays, nil
+}
+
+// parseICal reads the days covered by the VEVENTs of an iCalendar file.
+// Recurrence rules are not expanded, which suits holiday calendars, as they
+// list every year's dates.
+func parseICal(data []byte) (map[string]string, error) {
+	holidays := make(map[string]string)
+	var start, end time.Time
+	var summary string
+	inEvent := false
+	for _, line := range unfoldICal(data) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";") // drop parameters such as VALUE=DATE
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent, start, end, summary = true, time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			// Only the date matters; times are cut off rather than
+			// converted, as holidays are all-day events.
+			t, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", name, value)
+			}
+			if strings.EqualFold(name, "DTSTART") {
+				start = t
+			} else {
+				end = t
+			}
+		case "SUMMARY":
+			summary = icalUnescaper.Replace(value)
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			// DTEND is exclusive; a missing one means a single day.
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				holidays[d.Format(dateFormat)] = summary
+			}
+		}
+	}
+	return holidays, nil
+}
+
+var icalUnescaper = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`)
+
+// unfoldICal splits an iCalendar file into logical lines, joining lines
+// that were folded onto continuation lines starting with a space or tab.
+func unfoldICal(data []byte) []string {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
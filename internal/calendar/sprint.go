@@ -0,0 +1,192 @@
+package calendar
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSprintName names sprints when the calendar does not; {n} is
+// replaced by the sprint's number.
+const DefaultSprintName = "Sprint {n}"
+
+// Sprint is one sprint of a calendar. First and Last are its first and last
+// day, at midnight.
+type Sprint struct {
+	Number int
+	Name   string
+	First  time.Time
+	Last   time.Time
+}
+
+// Sprints is a sprint calendar: either a cadence of sprints of Length days,
+// the one starting on Start being number FirstNumber, or an explicit List
+// for teams whose sprints vary, numbered by position from 1. Dates are
+// civil dates at midnight UTC; methods return sprints in the location of
+// the time they are given.
+type Sprints struct {
+	Start       time.Time
+	Length      int
+	FirstNumber int
+	Name        string // with {n} for the number
+
+	List []Sprint
+}
+
+// ParseLength parses a sprint length such as "2w" or "10d" into days.
+func ParseLength(s string) (int, error) {
+	unit := 1
+	n := s
+	switch {
+	case strings.HasSuffix(s, "w"):
+		unit, n = 7, strings.TrimSuffix(s, "w")
+	case strings.HasSuffix(s, "d"):
+		n = strings.TrimSuffix(s, "d")
+	}
+	v, err := strconv.Atoi(n)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid length %q: must be a number of weeks or days, e.g. \"2w\" or \"10d\"", s)
+	}
+	return v * unit, nil
+}
+
+// NewCadence returns a calendar of sprints of length, such as "2w", the one
+// starting on start, as YYYY-MM-DD, being number first. name is the name
+// pattern, DefaultSprintName when empty; a zero first counts from 1.
+func NewCadence(start, length string, first int, name string) (*Sprints, error) {
+	t, err := parseDate("start", start)
+	if err != nil {
+		return nil, err
+	}
+	days, err := ParseLength(length)
+	if err != nil {
+		return nil, err
+	}
+	if first == 0 {
+		first = 1
+	}
+	if name == "" {
+		name = DefaultSprintName
+	}
+	return &Sprints{Start: t, Length: days, FirstNumber: first, Name: name}, nil
+}
+
+// SprintDates is an entry of a sprint list, with its first and last day
+// as YYYY-MM-DD.
+type SprintDates struct {
+	Name  string
+	Start string
+	End   string
+}
+
+// NewList returns a calendar of the given sprints. Sprints are numbered in
+// date order, and unnamed ones are named by DefaultSprintName.
+func NewList(sprints []SprintDates) (*Sprints, error) {
+	list := make([]Sprint, len(sprints))
+	for i, d := range sprints {
+		first, err := parseDate("start", d.Start)
+		if err != nil {
+			return nil, err
+		}
+		last, err := parseDate("end", d.End)
+		if err != nil {
+			return nil, err
+		}
+		if last.Before(first) {
+			return nil, fmt.Errorf("sprint starting %s ends before it starts", d.Start)
+		}
+		list[i] = Sprint{Name: d.Name, First: first, Last: last}
+	}
+	slices.SortFunc(list, func(a, b Sprint) int { return a.First.Compare(b.First) })
+	for i := range list {
+		if i > 0 && !list[i].First.After(list[i-1].Last) {
+			return nil, fmt.Errorf("sprints starting %s and %s overlap",
+				list[i-1].First.Format(dateFormat), list[i].First.Format(dateFormat))
+		}
+		list[i].Number = i + 1
+		if list[i].Name == "" {
+			list[i].Name = sprintName(DefaultSprintName, i+1)
+		}
+	}
+	return &Sprints{List: list}, nil
+}
+
+// Current returns the sprint that contains the day of t. Only a list can
+// have gaps without one.
+func (s Sprints) Current(t time.Time) (Sprint, bool) {
+	day := civil(t)
+	if s.List == nil {
+		days := int(day.Sub(s.Start).Hours() / 24)
+		n := days / s.Length
+		if days < 0 && days%s.Length != 0 {
+			n--
+		}
+		return s.cadence(s.FirstNumber + n).in(t.Location()), true
+	}
+	for _, sp := range s.List {
+		if !day.Before(sp.First) && !day.After(sp.Last) {
+			return sp.in(t.Location()), true
+		}
+	}
+	return Sprint{}, false
+}
+
+// Previous returns the last sprint that ended before the current one, or
+// before the day of t when it is in no sprint.
+func (s Sprints) Previous(t time.Time) (Sprint, bool) {
+	cur, ok := s.Current(t)
+	if s.List == nil {
+		return s.Number(cur.Number-1, t.Location())
+	}
+	before := civil(t)
+	if ok {
+		before = civil(cur.First)
+	}
+	for i := len(s.List) - 1; i >= 0; i-- {
+		if s.List[i].Last.Before(before) {
+			return s.List[i].in(t.Location()), true
+		}
+	}
+	return Sprint{}, false
+}
+
+// Number returns the sprint numbered n, in loc.
+func (s Sprints) Number(n int, loc *time.Location) (Sprint, bool) {
+	if s.List == nil {
+		return s.cadence(n).in(loc), true
+	}
+	if n < 1 || n > len(s.List) {
+		return Sprint{}, false
+	}
+	return s.List[n-1].in(loc), true
+}
+
+func (s Sprints) cadence(n int) Sprint {
+	first := s.Start.AddDate(0, 0, (n-s.FirstNumber)*s.Length)
+	return Sprint{Number: n, Name: sprintName(s.Name, n), First: first, Last: first.AddDate(0, 0, s.Length-1)}
+}
+
+func (sp Sprint) in(loc *time.Location) Sprint {
+	sp.First = time.Date(sp.First.Year(), sp.First.Month(), sp.First.Day(), 0, 0, 0, 0, loc)
+	sp.Last = time.Date(sp.Last.Year(), sp.Last.Month(), sp.Last.Day(), 0, 0, 0, 0, loc)
+	return sp
+}
+
+func sprintName(pattern string, n int) string {
+	return strings.ReplaceAll(pattern, "{n}", strconv.Itoa(n))
+}
+
+// civil returns the day of t as a civil date at midnight UTC.
+func civil(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func parseDate(field, s string) (time.Time, error) {
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: must be a date such as 2026-01-05", field, s)
+	}
+	return t, nil
+}
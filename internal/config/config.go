@@ -0,0 +1,356 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"worklog/internal/calendar"
+)
+
+// LocalFile is the name of the per-directory config file, which overrides
+// the global one when present in the working directory.
+const LocalFile = ".worklog.yaml"
+
+// Config is the contents of a worklog config file. The top-level settings
+// apply to every run; a profile selected with --profile overrides them.
+type Config struct {
+	Settings `yaml:",inline"`
+	Profiles map[string]Settings `yaml:"profiles"`
+
+	// TrustLocal lets the per-directory file configure accounts and publish
+	// targets. It is only read from the global file.
+	TrustLocal bool `yaml:"trust_local_config"`
+
+	// Warnings lists the settings of the per-directory file that Load left
+	// out.
+	Warnings []string `yaml:"-"`
+}
+
+// Settings are the options that can be given at the top level of a config
+// file or inside a profile. Empty fields leave the value from the layer below
+// (built-in defaults, the global file, the top level) unchanged.
+type Settings struct {
+	Since      string            `yaml:"since"`
+	Until      string            `yaml:"until"`
+	Output     string            `yaml:"output"`
+	Template   string            `yaml:"template"`
+	Notes      string            `yaml:"notes"`
+	Commits    string            `yaml:"commits"`
+	GroupBy    string            `yaml:"group_by"`
+	Timezone   string            `yaml:"timezone"`
+	WorkWeek   []string          `yaml:"work_week"`
+	Holidays   string            `yaml:"holidays"`
+	Sprint     *Sprint           `yaml:"sprint"`
+	Repos      Patterns          `yaml:"repos"`
+	Orgs       Patterns          `yaml:"orgs"`
+	Categories map[string]bool   `yaml:"categories"`
+	Aggregate  map[string]bool   `yaml:"aggregate"`
+	Accounts   []Account         `yaml:"accounts"`
+	Publish    map[string]Target `yaml:"publish"`
+}
+
+// Patterns lists path.Match patterns to include and exclude. Repo patterns
+// such as "acme/*" match "owner/repo" names; org patterns match the owner.
+type Patterns struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Sprint defines the sprint calendar: either sprints of Length, such as
+// "2w", counted from the Start of the one numbered Number, or an explicit
+// List. Name is the name pattern for the former, e.g. "Sprint {n}".
+type Sprint struct {
+	Start  string        `yaml:"start"`
+	Length string        `yaml:"length"`
+	Number int           `yaml:"number"`
+	Name   string        `yaml:"name"`
+	List   []SprintDates `yaml:"list"`
+}
+
+// SprintDates is one sprint of an explicit sprint list.
+type SprintDates struct {
+	Name  string `yaml:"name"`
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// Calendar returns the sprint calendar s defines.
+func (s Sprint) Calendar() (*calendar.Sprints, error) {
+	if s.List != nil {
+		if s.Start != "" || s.Length != "" || s.Number != 0 || s.Name != "" {
+			return nil, fmt.Errorf("a sprint list cannot be combined with start, length, number or name")
+		}
+		dates := make([]calendar.SprintDates, len(s.List))
+		for i, d := range s.List {
+			dates[i] = calendar.SprintDates(d)
+		}
+		return calendar.NewList(dates)
+	}
+	if s.Start == "" || s.Length == "" {
+		return nil, fmt.Errorf("sprints need either a start and a length, or a list")
+	}
+	return calendar.NewCadence(s.Start, s.Length, s.Number, s.Name)
+}
+
+// Account configures one provider account. Settings holds the provider's
+// keys in lower case (token, url, email, ...), which are the same keys it
+// reads from <PROVIDER>_* environment variables.
+type Account struct {
+	Provider string            `yaml:"provider"`
+	Label    string            `yaml:"label"`
+	Settings map[string]string `yaml:",inline"`
+}
+
+// Lookup returns the account setting for an environment-style key such as
+// "TOKEN", with ${VAR} references expanded from the environment.
+func (a Account) Lookup(key string) string {
+	return os.ExpandEnv(a.Settings[strings.ToLower(key)])
+}
+
+// Target is a destination the report can be published to. Type selects the
+// publisher and Settings holds its options.
+type Target struct {
+	Type     string            `yaml:"type"`
+	Settings map[string]string `yaml:",inline"`
+}
+
+// Lookup returns the target setting for an environment-style key such as
+// "WEBHOOK_URL", with ${VAR} references expanded from the environment.
+func (t Target) Lookup(key string) string {
+	return os.ExpandEnv(t.Settings[strings.ToLower(key)])
+}
+
+// GlobalPath returns the location of the user's config file,
+// $XDG_CONFIG_HOME/worklog/config.yaml or ~/.config/worklog/config.yaml.
+func GlobalPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "worklog", "config.yaml")
+}
+
+// Load reads the global config file and the per-directory file at local,
+// which overrides it key by key, and merges them. Missing files are skipped.
+// checkDate validates since and until values.
+//
+// The per-directory file may come with any cloned repository, so unless the
+// global file sets trust_local_config, its accounts and publish targets are
+// left out with a warning: an account URL of its choosing would receive the
+// token from the environment, and a publish target the report.
+func Load(checkDate func(string) error, global, local string) (*Config, error) {
+	cfg := &Config{}
+	for _, path := range []string{global, local} {
+		if path == "" {
+			continue
+		}
+		c, errs := parseFile(path, checkDate)
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		if c == nil {
+			continue
+		}
+		if path == local {
+			cfg.Warnings = append(cfg.Warnings, c.restrict(path, cfg.TrustLocal)...)
+		} else {
+			cfg.TrustLocal = c.TrustLocal
+		}
+		cfg.merge(c)
+	}
+	return cfg, nil
+}
+
+// restrict removes the settings an untrusted per-directory file at path may
+// not make, returning a warning for each.
+func (c *Config) restrict(path string, trusted bool) []string {
+	var warnings []string
+	if c.TrustLocal {
+		warnings = append(warnings, fmt.Sprintf("%s: ignoring trust_local_config, which only the global config file can set", path))
+	}
+	if trusted {
+		return warnings
+	}
+	ignore := func(what string) {
+		warnings = append(warnings, fmt.Sprintf("%s: ignoring %s; set trust_local_config: true in the global config file to allow them", path, what))
+	}
+	strip := func(where string, s *Settings) {
+		if s.Accounts != nil {
+			s.Accounts = nil
+			ignore("accounts" + where)
+		}
+		if s.Publish != nil {
+			s.Publish = nil
+			ignore("publish targets" + where)
+		}
+	}
+	strip("", &c.Settings)
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		p := c.Profiles[name]
+		strip(fmt.Sprintf(" in profile %q", name), &p)
+		c.Profiles[name] = p
+	}
+	return warnings
+}
+
+// Resolve returns the settings for the named profile layered over the
+// top-level settings. An empty name selects the top-level settings alone.
+func (c *Config) Resolve(profile string) (Settings, error) {
+	var s Settings
+	s.merge(c.Settings)
+	if profile == "" {
+		return s, nil
+	}
+	p, ok := c.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return Settings{}, fmt.Errorf("unknown profile %q: no profiles are defined", profile)
+		}
+		return Settings{}, fmt.Errorf("unknown profile %q: must be one of %s", profile, strings.Join(names, ", "))
+	}
+	s.merge(p)
+	return s, nil
+}
+
+func (c *Config) merge(o *Config) {
+	c.Settings.merge(o.Settings)
+	if len(o.Profiles) > 0 && c.Profiles == nil {
+		c.Profiles = make(map[string]Settings)
+	}
+	for name, p := range o.Profiles {
+		merged := c.Profiles[name]
+		merged.merge(p)
+		c.Profiles[name] = merged
+	}
+}
+
+// merge overrides s with the non-empty fields of o. Lists and the sprint
+// calendar replace each other, while category and aggregate toggles and
+// publish targets are merged by key.
+func (s *Settings) merge(o Settings) {
+	if o.Since != "" {
+		s.Since = o.Since
+	}
+	if o.Until != "" {
+		s.Until = o.Until
+	}
+	if o.Output != "" {
+		s.Output = o.Output
+	}
+	if o.Template != "" {
+		s.Template = o.Template
+	}
+	if o.Notes != "" {
+		s.Notes = o.Notes
+	}
+	if o.Commits != "" {
+		s.Commits = o.Commits
+	}
+	if o.GroupBy != "" {
+		s.GroupBy = o.GroupBy
+	}
+	if o.Timezone != "" {
+		s.Timezone = o.Timezone
+	}
+	if o.WorkWeek != nil {
+		s.WorkWeek = o.WorkWeek
+	}
+	if o.Holidays != "" {
+		s.Holidays = o.Holidays
+	}
+	if o.Sprint != nil {
+		s.Sprint = o.Sprint
+	}
+	if o.Repos.Include != nil {
+		s.Repos.Include = o.Repos.Include
+	}
+	if o.Repos.Exclude != nil {
+		s.Repos.Exclude = o.Repos.Exclude
+	}
+	if o.Orgs.Include != nil {
+		s.Orgs.Include = o.Orgs.Include
+	}
+	if o.Orgs.Exclude != nil {
+		s.Orgs.Exclude = o.Orgs.Exclude
+	}
+	if o.Accounts != nil {
+		s.Accounts = o.Accounts
+	}
+	if len(o.Categories) > 0 && s.Categories == nil {
+		s.Categories = make(map[string]bool)
+	}
+	for k, v := range o.Categories {
+		s.Categories[k] = v
+	}
+	if len(o.Aggregate) > 0 && s.Aggregate == nil {
+		s.Aggregate = make(map[string]bool)
+	}
+	for k, v := range o.Aggregate {
+		s.Aggregate[k] = v
+	}
+	if len(o.Publish) > 0 && s.Publish == nil {
+		s.Publish = make(map[string]Target)
+	}
+	for k, v := range o.Publish {
+		s.Publish[k] = v
+	}
+}
+
+// parseFile decodes and validates the config file at path. It returns a nil
+// config and no errors if the file does not exist.
+func parseFile(path string, checkDate func(string) error) (*Config, []error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, []error{yamlError(path, err.Error())}
+	}
+
+	var cfg Config
+	var errs []error
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, []error{yamlError(path, err.Error())}
+		}
+		for _, msg := range typeErr.Errors {
+			errs = append(errs, yamlError(path, msg))
+		}
+	}
+
+	errs = append(errs, check(path, &root, checkDate)...)
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errs[i].(*Error).Line < errs[j].(*Error).Line
+		})
+		return nil, errs
+	}
+	return &cfg, nil
+}
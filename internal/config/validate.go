@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"worklog/internal/calendar"
+	"worklog/internal/provider"
+	"worklog/internal/publish"
+	"worklog/internal/report"
+)
+
+// Error is a problem found in a config file, located by line when known.
+type Error struct {
+	Path string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+}
+
+// Validate checks the config file at path against the schema and returns
+// every problem found. checkDate validates since and until values.
+func Validate(path string, checkDate func(string) error) []error {
+	if _, err := os.Stat(path); err != nil {
+		return []error{err}
+	}
+	_, errs := parseFile(path, checkDate)
+	return errs
+}
+
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// yamlError converts a yaml.v3 message such as "line 4: field foo not found
+// in type config.Settings" into an Error.
+func yamlError(path, msg string) *Error {
+	e := &Error{Path: path, Msg: strings.TrimPrefix(msg, "yaml: ")}
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Msg = msg[len(m[0]):]
+	}
+	e.Msg = strings.Replace(e.Msg, " in type config.", " in ", 1)
+	return e
+}
+
+// check validates the values a strict decode cannot, such as output formats
+// and provider names, reporting the line of each offending node.
+func check(path string, root *yaml.Node, checkDate func(string) error) []error {
+	if len(root.Content) == 0 {
+		return nil
+	}
+	doc := root.Content[0]
+	c := &checker{path: path, checkDate: checkDate}
+	c.settings(doc)
+	if profiles := value(doc, "profiles"); profiles != nil && profiles.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(profiles.Content); i += 2 {
+			c.settings(profiles.Content[i+1])
+		}
+	}
+	return c.errs
+}
+
+type checker struct {
+	path      string
+	checkDate func(string) error
+	errs      []error
+}
+
+func (c *checker) errorf(n *yaml.Node, format string, args ...any) {
+	c.errs = append(c.errs, &Error{Path: c.path, Line: n.Line, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) settings(n *yaml.Node) {
+	for _, key := range []string{"since", "until"} {
+		if v := value(n, key); v != nil && c.checkDate != nil {
+			if err := c.checkDate(v.Value); err != nil {
+				c.errorf(v, "invalid %s %q: %v", key, v.Value, err)
+			}
+		}
+	}
+
+	if v := value(n, "output"); v != nil && !slices.Contains(report.Formats, v.Value) {
+		c.errorf(v, "invalid output %q: must be one of %s", v.Value, strings.Join(report.Formats, ", "))
+	}
+
+	if v := value(n, "commits"); v != nil && !slices.Contains(report.CommitModes, v.Value) {
+		c.errorf(v, "invalid commits %q: must be one of %s", v.Value, strings.Join(report.CommitModes, ", "))
+	}
+
+	if v := value(n, "group_by"); v != nil {
+		if _, err := report.ParseGroupBy(v.Value); err != nil {
+			c.errorf(v, "invalid group_by %q: %v", v.Value, err)
+		}
+	}
+
+	if v := value(n, "timezone"); v != nil {
+		if _, err := time.LoadLocation(v.Value); err != nil {
+			c.errorf(v, "invalid timezone %q: %v", v.Value, err)
+		}
+	}
+
+	if v := value(n, "work_week"); v != nil && v.Kind == yaml.SequenceNode {
+		for _, d := range v.Content {
+			if _, err := calendar.ParseWeekday(d.Value); err != nil {
+				c.errorf(d, "invalid work_week: %v", err)
+			}
+		}
+	}
+
+	if v := value(n, "holidays"); v != nil {
+		if _, err := calendar.LoadHolidays(v.Value); err != nil {
+			c.errorf(v, "invalid holidays: %v", err)
+		}
+	}
+
+	if v := value(n, "sprint"); v != nil && v.Kind == yaml.MappingNode {
+		var s Sprint
+		if err := v.Decode(&s); err == nil {
+			if _, err := s.Calendar(); err != nil {
+				c.errorf(v, "invalid sprint: %v", err)
+			}
+		}
+	}
+
+	if v := value(n, "template"); v != nil {
+		if _, err := report.LoadTemplate(v.Value); err != nil {
+			c.errorf(v, "invalid template: %v", err)
+		}
+	}
+
+	// The notes file may not have been written yet, but if it has, it must
+	// parse.
+	if v := value(n, "notes"); v != nil {
+		if f, err := os.Open(v.Value); err == nil {
+			_, err := report.ParseNotes(f)
+			f.Close()
+			if err != nil {
+				c.errorf(v, "invalid notes file %s: %v", v.Value, err)
+			}
+		}
+	}
+
+	for _, key := range []string{"categories", "aggregate"} {
+		if v := value(n, key); v != nil && v.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(v.Content); i += 2 {
+				k := v.Content[i]
+				if _, ok := report.ParseCategory(k.Value); !ok {
+					c.errorf(k, "unknown category %q: must be one of %s", k.Value, strings.Join(report.CategorySlugs(), ", "))
+				}
+			}
+		}
+	}
+
+	if v := value(n, "accounts"); v != nil && v.Kind == yaml.SequenceNode {
+		seen := make(map[string]bool)
+		for _, a := range v.Content {
+			p := value(a, "provider")
+			if p == nil {
+				c.errorf(a, "account is missing a provider")
+				continue
+			}
+			if !slices.Contains(provider.Names(), p.Value) {
+				c.errorf(p, "unknown provider %q: must be one of %s", p.Value, strings.Join(provider.Names(), ", "))
+				continue
+			}
+			id := p.Value
+			if l := value(a, "label"); l != nil && l.Value != "" {
+				id += ":" + l.Value
+			}
+			if seen[id] {
+				c.errorf(a, "duplicate account %s", id)
+			}
+			seen[id] = true
+		}
+	}
+
+	if v := value(n, "publish"); v != nil && v.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(v.Content); i += 2 {
+			t := value(v.Content[i+1], "type")
+			if t == nil {
+				c.errorf(v.Content[i], "publish target %q is missing a type", v.Content[i].Value)
+				continue
+			}
+			if !slices.Contains(publish.Types(), t.Value) {
+				c.errorf(t, "unknown publish type %q: must be one of %s", t.Value, strings.Join(publish.Types(), ", "))
+			}
+		}
+	}
+}
+
+// value returns the value node for key in mapping node n, or nil.
+func value(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// client is a minimal Gitea API v1 client. Forgejo serves the same API, so
+// it works against both.
+type client struct {
+	http    *http.Client
+	baseURL string
+	token   string
+}
+
+func newClient(token, baseURL string) *client {
+	return &client{
+		http:    http.DefaultClient,
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1",
+		token:   token,
+	}
+}
+
+// get issues a GET request for path with the given query parameters and
+// decodes the JSON response into v.
+func (c *client) get(ctx context.Context, path string, query map[string]string, v any) error {
+	u, err := url.Parse(c.baseURL + path)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	for k, val := range query {
+		q.Set(k, val)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type user struct {
+	Login string `json:"login"`
+}
+
+type repository struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+}
+
+type comment struct {
+	HTMLURL string `json:"html_url"`
+}
+
+type activity struct {
+	OpType  string      `json:"op_type"`
+	Content string      `json:"content"`
+	RefName string      `json:"ref_name"`
+	Repo    *repository `json:"repo"`
+	Comment *comment    `json:"comment"`
+	Created time.Time   `json:"created"`
+}
+
+type issue struct {
+	Number     int64      `json:"number"`
+	Title      string     `json:"title"`
+	HTMLURL    string     `json:"html_url"`
+	Repository repository `json:"repository"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type pullRequest struct {
+	Merged         bool   `json:"merged"`
+	MergeCommitSHA string `json:"merge_commit_sha"`
+	Head           struct {
+		Ref  string      `json:"ref"`
+		Repo *repository `json:"repo"`
+	} `json:"head"`
+}
+
+type commit struct {
+	SHA string `json:"sha"`
+}
+
+type workflowRun struct {
+	DisplayTitle string    `json:"display_title"`
+	HeadBranch   string    `json:"head_branch"`
+	HTMLURL      string    `json:"html_url"`
+	StartedAt    time.Time `json:"started_at"`
+}
@@ -0,0 +1,358 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"worklog/internal/provider"
+	"worklog/internal/report"
+)
+
+const defaultBaseURL = "https://gitea.com"
+
+func init() {
+	provider.Register("gitea", func() provider.Provider { return &Provider{} })
+}
+
+// Provider reports activity for the Gitea or Forgejo user owning GITEA_TOKEN
+// on the instance at GITEA_URL (gitea.com when unset).
+type Provider struct {
+	token   string
+	baseURL string
+	filter  report.RepoFilter
+}
+
+func (p *Provider) Name() string { return "gitea" }
+
+func (p *Provider) Configure(lookup func(string) string) (bool, error) {
+	p.token = lookup("TOKEN")
+	p.baseURL = lookup("URL")
+	return p.token != "", nil
+}
+
+func (p *Provider) Identity() string { return p.baseURL + "\x00" + p.token }
+
+func (p *Provider) SetRepoFilter(f report.RepoFilter) { p.filter = f }
+
+func (p *Provider) FetchEvents(ctx context.Context, since, until time.Time) ([]report.Event, error) {
+	return FetchEvents(ctx, p.token, p.baseURL, p.filter, since, until)
+}
+
+func FetchEvents(ctx context.Context, token, baseURL string, filter report.RepoFilter, since, until time.Time) ([]report.Event, error) {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	c := newClient(token, baseURL)
+
+	var u user
+	if err := c.get(ctx, "/user", nil, &u); err != nil {
+		return nil, fmt.Errorf("getting user: %w", err)
+	}
+
+	titles := newTitleCache(c)
+	repos := make(map[string]struct{})
+	var events []report.Event
+
+	const limit = 50
+	for page := 1; page <= 20; page++ {
+		var feed []activity
+		query := map[string]string{
+			"only-performed-by": "true",
+			"page":              strconv.Itoa(page),
+			"limit":             strconv.Itoa(limit),
+		}
+		if err := c.get(ctx, "/users/"+u.Login+"/activities/feeds", query, &feed); err != nil {
+			return nil, err
+		}
+		if len(feed) == 0 {
+			break
+		}
+		done := false
+		for _, a := range feed {
+			if a.Created.Before(since) {
+				done = true
+				break
+			}
+			if a.Created.After(until) || a.Repo == nil || !filter.Match(a.Repo.FullName) {
+				continue
+			}
+			repos[a.Repo.FullName] = struct{}{}
+			events = append(events, parseActivity(ctx, a, titles)...)
+		}
+		if done || len(feed) < limit {
+			break
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ciEvents, err := fetchCIFailures(ctx, c, u.Login, repos, since, until)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: gitea CI failures: %v\n", err)
+			return
+		}
+		mu.Lock()
+		events = append(events, ciEvents...)
+		mu.Unlock()
+	}()
+
+	go func() {
+		defer wg.Done()
+		prEvents, err := fetchPendingReviews(ctx, c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: gitea pending reviews: %v\n", err)
+			return
+		}
+		mu.Lock()
+		events = append(events, prEvents...)
+		mu.Unlock()
+	}()
+
+	wg.Wait()
+
+	err := report.LinkPullRequests(events, func(e report.Event) (report.PullRequestCommits, error) {
+		return readPullRequest(ctx, c, e)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: gitea pull request commits: %v\n", err)
+	}
+	return events, nil
+}
+
+// readPullRequest reads the source branch and commits of the pull request
+// of e for report.LinkPullRequests.
+func readPullRequest(ctx context.Context, c *client, e report.Event) (report.PullRequestCommits, error) {
+	var pr report.PullRequestCommits
+	var index int64
+	if _, err := fmt.Sscanf(e.Title, "#%d", &index); err != nil {
+		return pr, err
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d", e.Repo, index)
+	var pull pullRequest
+	if err := c.get(ctx, path, nil, &pull); err != nil {
+		return pr, err
+	}
+	var commits []commit
+	if err := c.get(ctx, path+"/commits", map[string]string{"limit": "50"}, &commits); err != nil {
+		return pr, err
+	}
+	if pull.Head.Repo != nil && pull.Head.Repo.FullName == e.Repo {
+		pr.Branch = pull.Head.Ref
+	}
+	for _, commit := range commits {
+		pr.SHAs = append(pr.SHAs, commit.SHA)
+	}
+	if pull.Merged && pull.MergeCommitSHA != "" {
+		pr.SHAs = append(pr.SHAs, pull.MergeCommitSHA)
+	}
+	return pr, nil
+}
+
+// fetchCIFailures lists failed Gitea Actions runs triggered by the user in each
+// repo seen in the activity feed. Instances without the runs API (older Gitea,
+// some Forgejo releases) are skipped silently, matching the other providers.
+func fetchCIFailures(ctx context.Context, c *client, username string, repos map[string]struct{}, since, until time.Time) ([]report.Event, error) {
+	var events []report.Event
+	for repoName := range repos {
+		var result struct {
+			WorkflowRuns []workflowRun `json:"workflow_runs"`
+		}
+		query := map[string]string{
+			"status": "failure",
+			"actor":  username,
+			"limit":  "50",
+		}
+		if err := c.get(ctx, "/repos/"+repoName+"/actions/runs", query, &result); err != nil {
+			continue
+		}
+		for _, run := range result.WorkflowRuns {
+			if run.StartedAt.Before(since) || run.StartedAt.After(until) {
+				continue
+			}
+			events = append(events, report.Event{
+				Category:  report.CategoryPipeline,
+				Action:    "failed",
+				Title:     fmt.Sprintf("%s on %s", run.DisplayTitle, run.HeadBranch),
+				URL:       run.HTMLURL,
+				Repo:      repoName,
+				Source:    "gitea",
+				CreatedAt: run.StartedAt,
+			})
+		}
+	}
+	return events, nil
+}
+
+func fetchPendingReviews(ctx context.Context, c *client) ([]report.Event, error) {
+	var issues []issue
+	query := map[string]string{
+		"type":             "pulls",
+		"state":            "open",
+		"review_requested": "true",
+		"limit":            "50",
+	}
+	if err := c.get(ctx, "/repos/issues/search", query, &issues); err != nil {
+		return nil, err
+	}
+
+	var events []report.Event
+	for _, is := range issues {
+		events = append(events, report.Event{
+			Category:  report.CategoryPendingReview,
+			Action:    "awaiting your review",
+			Title:     fmt.Sprintf("#%d %s", is.Number, is.Title),
+			URL:       is.HTMLURL,
+			Repo:      is.Repository.FullName,
+			Source:    "gitea",
+			CreatedAt: is.CreatedAt,
+		})
+	}
+	return events, nil
+}
+
+// titleCache resolves issue and pull request titles for activities whose
+// content carries comment or review text instead of the title.
+type titleCache struct {
+	client *client
+	titles map[string]string
+}
+
+func newTitleCache(c *client) *titleCache {
+	return &titleCache{client: c, titles: make(map[string]string)}
+}
+
+func (tc *titleCache) lookup(ctx context.Context, repo string, index int64) string {
+	key := fmt.Sprintf("%s#%d", repo, index)
+	if t, ok := tc.titles[key]; ok {
+		return t
+	}
+	var is issue
+	if err := tc.client.get(ctx, fmt.Sprintf("/repos/%s/issues/%d", repo, index), nil, &is); err != nil {
+		return ""
+	}
+	tc.titles[key] = is.Title
+	return is.Title
+}
+
+func parseActivity(ctx context.Context, a activity, titles *titleCache) []report.Event {
+	repoName := a.Repo.FullName
+	repoURL := a.Repo.HTMLURL
+
+	// item builds the event for an issue or pull request activity. Only
+	// creation and merge activities carry the title in their content; the
+	// others carry comment or review text, so the title is looked up.
+	item := func(cat report.EventCategory, action, kind string, titleInContent bool) []report.Event {
+		index, title := splitContent(a.Content)
+		if index == 0 {
+			return nil
+		}
+		if !titleInContent {
+			title = titles.lookup(ctx, repoName, index)
+		}
+		url := fmt.Sprintf("%s/%s/%d", repoURL, kind, index)
+		if a.Comment != nil && a.Comment.HTMLURL != "" {
+			url = a.Comment.HTMLURL
+		}
+		return []report.Event{{
+			Category:  cat,
+			Action:    action,
+			Title:     fmt.Sprintf("#%d %s", index, title),
+			URL:       url,
+			Repo:      repoName,
+			Source:    "gitea",
+			CreatedAt: a.Created,
+		}}
+	}
+
+	switch a.OpType {
+	case "commit_repo":
+		return parsePush(a)
+
+	case "create_pull_request":
+		return item(report.CategoryPR, "opened", "pulls", true)
+	case "merge_pull_request", "auto_merge_pull_request":
+		return item(report.CategoryPR, "merged", "pulls", true)
+	case "close_pull_request":
+		return item(report.CategoryPR, "closed", "pulls", false)
+	case "reopen_pull_request":
+		return item(report.CategoryPR, "reopened", "pulls", false)
+
+	case "approve_pull_request":
+		return item(report.CategoryReview, "approved", "pulls", false)
+	case "reject_pull_request":
+		return item(report.CategoryReview, "changes requested", "pulls", false)
+	case "comment_pull":
+		return item(report.CategoryReviewComment, "commented", "pulls", false)
+
+	case "create_issue":
+		return item(report.CategoryIssue, "opened", "issues", true)
+	case "close_issue":
+		return item(report.CategoryIssue, "closed", "issues", false)
+	case "reopen_issue":
+		return item(report.CategoryIssue, "reopened", "issues", false)
+	case "comment_issue":
+		return item(report.CategoryComment, "commented", "issues", false)
+	}
+	return nil
+}
+
+// parsePush expands a commit_repo activity, whose content is a JSON-encoded
+// list of pushed commits, into one event per commit.
+func parsePush(a activity) []report.Event {
+	var push struct {
+		Commits []struct {
+			Sha1    string
+			Message string
+		}
+	}
+	_ = json.Unmarshal([]byte(a.Content), &push)
+
+	branch := strings.TrimPrefix(a.RefName, "refs/heads/")
+	if len(push.Commits) == 0 {
+		return []report.Event{{
+			Category:  report.CategoryCommit,
+			Action:    "pushed",
+			Title:     fmt.Sprintf("to %s", branch),
+			Repo:      a.Repo.FullName,
+			Source:    "gitea",
+			Branch:    branch,
+			CreatedAt: a.Created,
+		}}
+	}
+	var evts []report.Event
+	for _, c := range push.Commits {
+		evts = append(evts, report.Event{
+			Category:  report.CategoryCommit,
+			Action:    "pushed",
+			Title:     report.FirstLine(c.Message),
+			URL:       fmt.Sprintf("%s/commit/%s", a.Repo.HTMLURL, c.Sha1),
+			Repo:      a.Repo.FullName,
+			Source:    "gitea",
+			SHA:       c.Sha1,
+			Branch:    branch,
+			CreatedAt: a.Created,
+		})
+	}
+	return evts
+}
+
+// splitContent parses the "<index>|<text>" content used by issue and pull
+// request activities.
+func splitContent(content string) (int64, string) {
+	indexStr, text, _ := strings.Cut(content, "|")
+	index, err := strconv.ParseInt(indexStr, 10, 64)
+	if err != nil {
+		return 0, ""
+	}
+	return index, report.FirstLine(text)
+}
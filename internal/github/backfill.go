@@ -0,0 +1,250 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	gh "github.com/google/go-github/v69/github"
+	"worklog/internal/report"
+)
+
+// The activity feed returns at most 300 events from the last 90 days.
+const (
+	feedMaxEvents = 300
+	feedMaxAge    = 90 * 24 * time.Hour
+)
+
+// The search API returns at most 1000 results per query, so backfill
+// searches are split into date windows, halved while a window holds more.
+const (
+	searchMaxResults = 1000
+	backfillWindow   = 30 * 24 * time.Hour
+	minBackfillSpan  = 24 * time.Hour
+)
+
+// feedLimited reports whether err is GitHub refusing to page further into
+// the activity feed.
+func feedLimited(err error) bool {
+	var errResp *gh.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil &&
+		errResp.Response.StatusCode == http.StatusUnprocessableEntity
+}
+
+// backfill recovers the activity between since and before, the time of the
+// oldest event in the truncated feed, from the search API: pull requests
+// and issues the user opened, pull requests merged, and the reviews and
+// comments the user left. It returns warnings for parts of the range it
+// could not cover.
+func backfill(ctx context.Context, client *gh.Client, username string, filter report.RepoFilter, since, before time.Time) ([]report.Event, []string) {
+	b := &backfiller{client: client, username: username, since: since, before: before}
+	q := searchQualifiers(filter)
+
+	b.windowed(ctx, "is:pr author:"+username+q, "created", func(item *gh.Issue) {
+		b.add(report.CategoryPR, "opened", item, item.GetHTMLURL(), item.GetCreatedAt().Time)
+	})
+	b.windowed(ctx, "is:pr is:merged author:"+username+q, "merged", func(item *gh.Issue) {
+		b.add(report.CategoryPR, "merged", item, item.GetHTMLURL(), item.GetPullRequestLinks().GetMergedAt().Time)
+	})
+	b.windowed(ctx, "is:issue author:"+username+q, "created", func(item *gh.Issue) {
+		b.add(report.CategoryIssue, "opened", item, item.GetHTMLURL(), item.GetCreatedAt().Time)
+	})
+
+	// Reviews and comments cannot be searched by their own date. Items
+	// updated since the start of the range include every item the user
+	// reviewed or commented on within it; the timestamps come from the
+	// item's reviews and comments.
+	b.updated(ctx, "is:pr reviewed-by:"+username+" -author:"+username+q, b.reviews)
+	b.updated(ctx, "commenter:"+username+q, b.comments)
+
+	return b.events, b.warnings
+}
+
+type backfiller struct {
+	client   *gh.Client
+	username string
+	since    time.Time
+	before   time.Time
+
+	events   []report.Event
+	warnings []string
+	seen     map[string]bool // event IDs, as windows overlap at their edges
+}
+
+func (b *backfiller) warnf(format string, args ...any) {
+	b.warnings = append(b.warnings, fmt.Sprintf(format, args...))
+}
+
+// add records an event for item if at falls in the backfilled range.
+func (b *backfiller) add(cat report.EventCategory, action string, item *gh.Issue, url string, at time.Time) {
+	if at.Before(b.since) || !at.Before(b.before) {
+		return
+	}
+	e := report.Event{
+		Category:  cat,
+		Action:    action,
+		Title:     fmt.Sprintf("#%d %s", item.GetNumber(), item.GetTitle()),
+		URL:       url,
+		Repo:      issueRepo(item),
+		Source:    "github",
+		CreatedAt: at,
+	}
+	if b.seen == nil {
+		b.seen = make(map[string]bool)
+	}
+	if b.seen[e.ID()] {
+		return
+	}
+	b.seen[e.ID()] = true
+	b.events = append(b.events, e)
+}
+
+// windowed runs query restricted to windows of the backfilled range on the
+// given date qualifier, calling fn for each result.
+func (b *backfiller) windowed(ctx context.Context, query, field string, fn func(*gh.Issue)) {
+	for from := b.since; from.Before(b.before); from = from.Add(backfillWindow) {
+		to := from.Add(backfillWindow)
+		if to.After(b.before) {
+			to = b.before
+		}
+		b.window(ctx, query, field, from, to, fn)
+	}
+}
+
+func (b *backfiller) window(ctx context.Context, query, field string, from, to time.Time, fn func(*gh.Issue)) {
+	q := fmt.Sprintf("%s %s:%s..%s", query, field, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+	items, total, err := searchAll(ctx, b.client, q)
+	if err != nil {
+		b.warnf("searching %s between %s and %s: %v", field, from.Format("Jan 2"), to.Format("Jan 2"), err)
+		return
+	}
+	if total > searchMaxResults {
+		if span := to.Sub(from); span > minBackfillSpan {
+			mid := from.Add(span / 2)
+			b.window(ctx, query, field, from, mid, fn)
+			b.window(ctx, query, field, mid, to, fn)
+			return
+		}
+		b.warnf("more than %d results for %q on %s; some are missing", searchMaxResults, query, from.Format("Jan 2"))
+	}
+	for _, item := range items {
+		fn(item)
+	}
+}
+
+// updated runs query for items updated since the start of the range and
+// calls fn for each.
+func (b *backfiller) updated(ctx context.Context, query string, fn func(context.Context, *gh.Issue) error) {
+	q := fmt.Sprintf("%s updated:>=%s", query, b.since.UTC().Format(time.RFC3339))
+	items, total, err := searchAll(ctx, b.client, q)
+	if err != nil {
+		b.warnf("searching %q: %v", query, err)
+		return
+	}
+	if total > searchMaxResults {
+		b.warnf("more than %d results for %q; some reviews and comments are missing", searchMaxResults, query)
+	}
+	for _, item := range items {
+		if err := fn(ctx, item); err != nil {
+			b.warnf("%s#%d: %v", issueRepo(item), item.GetNumber(), err)
+		}
+	}
+}
+
+// reviews adds the user's reviews and inline review comments on the pull
+// request item.
+func (b *backfiller) reviews(ctx context.Context, item *gh.Issue) error {
+	owner, repo, ok := strings.Cut(issueRepo(item), "/")
+	if !ok {
+		return nil
+	}
+
+	opts := &gh.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := b.client.PullRequests.ListReviews(ctx, owner, repo, item.GetNumber(), opts)
+		if err != nil {
+			return err
+		}
+		for _, r := range reviews {
+			if strings.EqualFold(r.GetUser().GetLogin(), b.username) {
+				b.add(report.CategoryReview, reviewAction(r.GetState()), item, r.GetHTMLURL(), r.GetSubmittedAt().Time)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	copts := &gh.PullRequestListCommentsOptions{Since: b.since, ListOptions: gh.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := b.client.PullRequests.ListComments(ctx, owner, repo, item.GetNumber(), copts)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			if strings.EqualFold(c.GetUser().GetLogin(), b.username) {
+				b.add(report.CategoryReviewComment, "commented", item, c.GetHTMLURL(), c.GetCreatedAt().Time)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		copts.Page = resp.NextPage
+	}
+	return nil
+}
+
+// comments adds the user's comments on the issue or pull request item.
+func (b *backfiller) comments(ctx context.Context, item *gh.Issue) error {
+	owner, repo, ok := strings.Cut(issueRepo(item), "/")
+	if !ok {
+		return nil
+	}
+	opts := &gh.IssueListCommentsOptions{Since: &b.since, ListOptions: gh.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := b.client.Issues.ListComments(ctx, owner, repo, item.GetNumber(), opts)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			if strings.EqualFold(c.GetUser().GetLogin(), b.username) {
+				b.add(report.CategoryComment, "commented", item, c.GetHTMLURL(), c.GetCreatedAt().Time)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return nil
+}
+
+// searchAll returns every result of an issue search the API will page
+// through, and the total number of matches.
+func searchAll(ctx context.Context, client *gh.Client, query string) ([]*gh.Issue, int, error) {
+	var items []*gh.Issue
+	opts := &gh.SearchOptions{ListOptions: gh.ListOptions{PerPage: 100}}
+	for {
+		result, resp, err := client.Search.Issues(ctx, query, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, result.Issues...)
+		if resp.NextPage == 0 || len(items) >= searchMaxResults {
+			return items, result.GetTotal(), nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// issueRepo returns the "owner/repo" name of a search result.
+func issueRepo(item *gh.Issue) string {
+	if _, name, ok := strings.Cut(item.GetRepositoryURL(), "/repos/"); ok {
+		return name
+	}
+	return ""
+}
@@ -2,22 +2,87 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
 	gh "github.com/google/go-github/v69/github"
+	"worklog/internal/provider"
 	"worklog/internal/report"
 )
 
-func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]report.Event, error) {
-	client := gh.NewClient(nil).WithAuthToken(token)
+func init() {
+	provider.Register("github", func() provider.Provider { return &Provider{} })
+}
+
+// Provider reports activity for the GitHub user owning GITHUB_TOKEN on
+// github.com, or on the GitHub Enterprise Server at GITHUB_URL when set.
+// GITHUB_BACKEND selects the API it is read from: "rest" (the default) for
+// the activity feed, or "graphql" for the contributions collection.
+type Provider struct {
+	token     string
+	baseURL   string
+	uploadURL string
+	backend   string
+	filter    report.RepoFilter
+	warnings  []string
+}
+
+func (p *Provider) Name() string { return "github" }
+
+func (p *Provider) Configure(lookup func(string) string) (bool, error) {
+	p.token = lookup("TOKEN")
+	p.baseURL = lookup("URL")
+	p.uploadURL = lookup("UPLOAD_URL")
+	p.backend = lookup("BACKEND")
+	switch p.backend {
+	case "", "rest", "graphql":
+	default:
+		return false, fmt.Errorf("unknown backend %q: must be \"rest\" or \"graphql\"", p.backend)
+	}
+	return p.token != "", nil
+}
+
+func (p *Provider) Identity() string { return p.baseURL + "\x00" + p.token }
+
+func (p *Provider) SetRepoFilter(f report.RepoFilter) { p.filter = f }
+
+func (p *Provider) FetchEvents(ctx context.Context, since, until time.Time) ([]report.Event, error) {
+	var events []report.Event
+	var warnings []string
+	var err error
+	if p.backend == "graphql" {
+		events, warnings, err = fetchGraphQL(ctx, p.token, p.baseURL, p.filter, since, until)
+	} else {
+		events, warnings, err = FetchEvents(ctx, p.token, p.baseURL, p.uploadURL, p.filter, since, until)
+	}
+	p.warnings = warnings
+	return events, err
+}
+
+// Warnings returns the gaps in coverage found by the last FetchEvents, such
+// as parts of the range beyond the activity feed that search could not
+// backfill.
+func (p *Provider) Warnings() []string { return p.warnings }
+
+// FetchEvents returns the user's activity between since and until, and
+// warnings about parts of the range it could not cover.
+func FetchEvents(ctx context.Context, token, baseURL, uploadURL string, filter report.RepoFilter, since, until time.Time) ([]report.Event, []string, error) {
+	client, err := newClient(token, baseURL, uploadURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	webURL := webBaseURL(baseURL)
+	enterprise := baseURL != ""
 
 	u, _, err := client.Users.Get(ctx, "")
 	if err != nil {
-		return nil, fmt.Errorf("getting user: %w", err)
+		return nil, nil, fmt.Errorf("getting user: %w", err)
 	}
 	username := u.GetLogin()
 
@@ -27,27 +92,44 @@ func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]r
 	// search (phase 2) can skip duplicates.
 	seenSHAs := make(map[string]struct{})
 
+	// The feed is capped, so it may end before reaching since. oldest is the
+	// time of its last event, from where the rest of the range is backfilled.
+	done := false
+	feedLimitHit := false
+	feedCount := 0
+	oldest := until
+
 	opts := &gh.ListOptions{PerPage: 100}
-	for page := 1; page <= 10; page++ {
+	for page := 1; page <= 10 && !done; page++ {
 		opts.Page = page
 		ghEvents, _, err := client.Activity.ListEventsPerformedByUser(ctx, username, false, opts)
 		if err != nil {
-			return nil, err
+			if page > 1 && feedLimited(err) {
+				feedLimitHit = true
+				break
+			}
+			return nil, nil, err
 		}
 		if len(ghEvents) == 0 {
 			break
 		}
-		done := false
 		for _, e := range ghEvents {
 			createdAt := e.GetCreatedAt().Time
 			if createdAt.Before(since) {
 				done = true
 				break
 			}
+			feedCount++
+			if createdAt.Before(oldest) {
+				oldest = createdAt
+			}
 			if createdAt.After(until) {
 				continue
 			}
 			repoName := e.GetRepo().GetName()
+			if !filter.Match(repoName) {
+				continue
+			}
 			repos[repoName] = struct{}{}
 			if e.GetType() == "PushEvent" {
 				if p, err := e.ParsePayload(); err == nil {
@@ -58,10 +140,27 @@ func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]r
 					}
 				}
 			}
-			events = append(events, parseEvent(e)...)
+			events = append(events, parseEvent(e, webURL)...)
 		}
-		if done {
-			break
+	}
+
+	var warnings []string
+	truncated := !done && (feedLimitHit || feedCount >= feedMaxEvents || time.Since(since) > feedMaxAge)
+	if truncated {
+		backfilled, bfWarnings := backfill(ctx, client, username, filter, since, oldest)
+		events = append(events, backfilled...)
+		for _, e := range backfilled {
+			if filter.Match(e.Repo) {
+				repos[e.Repo] = struct{}{}
+			}
+		}
+		if enterprise && len(backfilled) == 0 && len(bfWarnings) > 0 {
+			warnings = append(warnings, fmt.Sprintf("the activity feed only reaches back to %s and search is not available on %s to cover the rest",
+				oldest.Format("Jan 2"), webURL))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("the activity feed only reaches back to %s; earlier activity was recovered from search, "+
+				"which misses issues and pull requests you closed or reopened", oldest.Format("Jan 2")))
+			warnings = append(warnings, bfWarnings...)
 		}
 	}
 
@@ -83,8 +182,12 @@ func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]r
 
 	go func() {
 		defer wg.Done()
-		prEvents, err := fetchPendingReviews(ctx, client, username)
+		prEvents, err := fetchPendingReviews(ctx, client, username, filter)
 		if err != nil {
+			if enterprise && searchUnavailable(err) {
+				fmt.Fprintf(os.Stderr, "warning: github pending reviews: search is not available on %s\n", webURL)
+				return
+			}
 			fmt.Fprintf(os.Stderr, "warning: github pending reviews: %v\n", err)
 			return
 		}
@@ -95,8 +198,12 @@ func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]r
 
 	go func() {
 		defer wg.Done()
-		commitEvents, err := fetchCommits(ctx, client, username, since, until, seenSHAs)
+		commitEvents, err := fetchCommits(ctx, client, username, filter, since, until, seenSHAs)
 		if err != nil {
+			if enterprise && searchUnavailable(err) {
+				fmt.Fprintf(os.Stderr, "warning: github commit search is not available on %s; commits are taken from the activity feed only\n", webURL)
+				return
+			}
 			fmt.Fprintf(os.Stderr, "warning: github commit search: %v\n", err)
 			return
 		}
@@ -106,7 +213,85 @@ func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]r
 	}()
 
 	wg.Wait()
-	return events, nil
+
+	err = report.LinkPullRequests(events, func(e report.Event) (report.PullRequestCommits, error) {
+		return readPullRequest(ctx, client, e)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: github pull request commits: %v\n", err)
+	}
+	return events, warnings, nil
+}
+
+// readPullRequest reads the source branch and commits of the pull request
+// of e for report.LinkPullRequests.
+func readPullRequest(ctx context.Context, client *gh.Client, e report.Event) (report.PullRequestCommits, error) {
+	var pr report.PullRequestCommits
+	owner, repo, _ := strings.Cut(e.Repo, "/")
+	var number int
+	if _, err := fmt.Sscanf(e.Title, "#%d", &number); err != nil {
+		return pr, err
+	}
+	pull, _, err := client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return pr, err
+	}
+	commits, _, err := client.PullRequests.ListCommits(ctx, owner, repo, number, &gh.ListOptions{PerPage: 100})
+	if err != nil {
+		return pr, err
+	}
+	if pull.GetHead().GetRepo().GetFullName() == e.Repo {
+		pr.Branch = pull.GetHead().GetRef()
+	}
+	for _, c := range commits {
+		pr.SHAs = append(pr.SHAs, c.GetSHA())
+	}
+	if pull.GetMerged() {
+		pr.SHAs = append(pr.SHAs, pull.GetMergeCommitSHA())
+	}
+	return pr, nil
+}
+
+// newClient returns a client for github.com, or for the GitHub Enterprise
+// Server at baseURL when set. The upload URL defaults to the base URL, from
+// which go-github derives the /api/uploads/ endpoint.
+func newClient(token, baseURL, uploadURL string) (*gh.Client, error) {
+	client := gh.NewClient(nil).WithAuthToken(token)
+	if baseURL == "" {
+		return client, nil
+	}
+	if uploadURL == "" {
+		uploadURL = baseURL
+	}
+	return client.WithEnterpriseURLs(strings.TrimRight(baseURL, "/"), strings.TrimRight(uploadURL, "/"))
+}
+
+// webBaseURL returns the root of the web UI for the instance at baseURL,
+// used to build links the API does not return.
+func webBaseURL(baseURL string) string {
+	if baseURL == "" {
+		return "https://github.com"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return strings.TrimRight(baseURL, "/")
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// searchUnavailable reports whether err indicates that the search API is
+// missing or disabled, as on GitHub Enterprise Server instances without a
+// configured search index or with commit search turned off.
+func searchUnavailable(err error) bool {
+	var errResp *gh.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	switch errResp.Response.StatusCode {
+	case http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
 }
 
 func fetchCIFailures(ctx context.Context, client *gh.Client, username string, repos map[string]struct{}, since, until time.Time) ([]report.Event, error) {
@@ -120,7 +305,7 @@ func fetchCIFailures(ctx context.Context, client *gh.Client, username string, re
 		opts := &gh.ListWorkflowRunsOptions{
 			Actor:       username,
 			Status:      "failure",
-			Created:     ">=" + since.Format("2006-01-02"),
+			Created:     ">=" + since.UTC().Format(time.RFC3339),
 			ListOptions: gh.ListOptions{PerPage: 100},
 		}
 		result, _, err := client.Actions.ListRepositoryWorkflowRuns(ctx, owner, repo, opts)
@@ -129,7 +314,7 @@ func fetchCIFailures(ctx context.Context, client *gh.Client, username string, re
 		}
 		for _, run := range result.WorkflowRuns {
 			createdAt := run.GetCreatedAt().Time
-			if createdAt.After(until) {
+			if createdAt.Before(since) || createdAt.After(until) {
 				continue
 			}
 			events = append(events, report.Event{
@@ -146,8 +331,8 @@ func fetchCIFailures(ctx context.Context, client *gh.Client, username string, re
 	return events, nil
 }
 
-func fetchPendingReviews(ctx context.Context, client *gh.Client, username string) ([]report.Event, error) {
-	query := fmt.Sprintf("is:pr is:open review-requested:%s", username)
+func fetchPendingReviews(ctx context.Context, client *gh.Client, username string, filter report.RepoFilter) ([]report.Event, error) {
+	query := fmt.Sprintf("is:pr is:open review-requested:%s", username) + searchQualifiers(filter)
 	opts := &gh.SearchOptions{ListOptions: gh.ListOptions{PerPage: 100}}
 	result, _, err := client.Search.Issues(ctx, query, opts)
 	if err != nil {
@@ -156,16 +341,12 @@ func fetchPendingReviews(ctx context.Context, client *gh.Client, username string
 
 	var events []report.Event
 	for _, item := range result.Issues {
-		repoName := ""
-		if parts := strings.SplitN(item.GetRepositoryURL(), "/repos/", 2); len(parts) == 2 {
-			repoName = parts[1]
-		}
 		events = append(events, report.Event{
 			Category:  report.CategoryPendingReview,
 			Action:    "awaiting your review",
 			Title:     fmt.Sprintf("#%d %s", item.GetNumber(), item.GetTitle()),
 			URL:       item.GetHTMLURL(),
-			Repo:      repoName,
+			Repo:      issueRepo(item),
 			Source:    "github",
 			CreatedAt: item.GetCreatedAt().Time,
 		})
@@ -176,9 +357,9 @@ func fetchPendingReviews(ctx context.Context, client *gh.Client, username string
 // fetchCommits uses the commit search API to find commits the user authored
 // in all accessible repos (including private ones). It skips SHAs already
 // seen in the event stream to avoid duplicates.
-func fetchCommits(ctx context.Context, client *gh.Client, username string, since, until time.Time, seenSHAs map[string]struct{}) ([]report.Event, error) {
+func fetchCommits(ctx context.Context, client *gh.Client, username string, filter report.RepoFilter, since, until time.Time, seenSHAs map[string]struct{}) ([]report.Event, error) {
 	query := fmt.Sprintf("author:%s author-date:%s..%s", username,
-		since.Format("2006-01-02"), until.Format("2006-01-02"))
+		since.UTC().Format(time.RFC3339), until.UTC().Format(time.RFC3339)) + searchQualifiers(filter)
 
 	var events []report.Event
 	opts := &gh.SearchOptions{ListOptions: gh.ListOptions{PerPage: 100}}
@@ -195,10 +376,11 @@ func fetchCommits(ctx context.Context, client *gh.Client, username string, since
 			events = append(events, report.Event{
 				Category:  report.CategoryCommit,
 				Action:    "pushed",
-				Title:     firstLine(c.GetCommit().GetMessage()),
+				Title:     report.FirstLine(c.GetCommit().GetMessage()),
 				URL:       c.GetHTMLURL(),
 				Repo:      c.GetRepository().GetFullName(),
 				Source:    "github",
+				SHA:       c.GetSHA(),
 				CreatedAt: authorDate,
 			})
 		}
@@ -210,7 +392,38 @@ func fetchCommits(ctx context.Context, client *gh.Client, username string, since
 	return events, nil
 }
 
-func parseEvent(e *gh.Event) []report.Event {
+// searchQualifiers turns the literal patterns of filter into search
+// qualifiers so that filtered-out repositories are not fetched. Glob patterns
+// cannot be expressed in search syntax and are left to the report filter.
+// The user: qualifier is used for organizations because, unlike org:, it
+// also accepts personal accounts.
+func searchQualifiers(filter report.RepoFilter) string {
+	var q []string
+	if orgs, repos, ok := filter.LiteralIncludes(); ok {
+		for _, org := range orgs {
+			q = append(q, "user:"+org)
+		}
+		for _, repo := range repos {
+			q = append(q, "repo:"+repo)
+		}
+	}
+	for _, org := range filter.ExcludeOrgs {
+		if report.IsLiteral(org) {
+			q = append(q, "-user:"+org)
+		}
+	}
+	for _, repo := range filter.ExcludeRepos {
+		if report.IsLiteral(repo) {
+			q = append(q, "-repo:"+repo)
+		}
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return " " + strings.Join(q, " ")
+}
+
+func parseEvent(e *gh.Event, webURL string) []report.Event {
 	payload, err := e.ParsePayload()
 	if err != nil {
 		return nil
@@ -228,17 +441,22 @@ func parseEvent(e *gh.Event) []report.Event {
 				Title:     fmt.Sprintf("to %s", branch),
 				Repo:      repoName,
 				Source:    "github",
+				Branch:    branch,
 				CreatedAt: createdAt,
 			}}
 		}
+		branch := strings.TrimPrefix(p.GetRef(), "refs/heads/")
 		var evts []report.Event
 		for _, c := range p.Commits {
 			evts = append(evts, report.Event{
 				Category:  report.CategoryCommit,
 				Action:    "pushed",
-				Title:     firstLine(c.GetMessage()),
+				Title:     report.FirstLine(c.GetMessage()),
+				URL:       fmt.Sprintf("%s/%s/commit/%s", webURL, repoName, c.GetSHA()),
 				Repo:      repoName,
+				SHA:       c.GetSHA(),
 				Source:    "github",
+				Branch:    branch,
 				CreatedAt: createdAt,
 			})
 		}
@@ -262,7 +480,7 @@ func parseEvent(e *gh.Event) []report.Event {
 	case *gh.PullRequestReviewEvent:
 		return []report.Event{{
 			Category:  report.CategoryReview,
-			Action:    p.GetReview().GetState(),
+			Action:    reviewAction(p.GetReview().GetState()),
 			Title:     fmt.Sprintf("#%d %s", p.GetPullRequest().GetNumber(), p.GetPullRequest().GetTitle()),
 			URL:       p.GetReview().GetHTMLURL(),
 			Repo:      repoName,
@@ -306,11 +524,9 @@ func parseEvent(e *gh.Event) []report.Event {
 	return nil
 }
 
-func firstLine(s string) string {
-	for i := range s {
-		if s[i] == '\n' {
-			return s[:i]
-		}
-	}
-	return s
+// reviewAction turns a review state such as "CHANGES_REQUESTED" or
+// "changes_requested" into the action other providers report, "changes
+// requested".
+func reviewAction(state string) string {
+	return strings.ReplaceAll(strings.ToLower(state), "_", " ")
 }
@@ -0,0 +1,765 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"worklog/internal/report"
+)
+
+// graphqlClient is a minimal client for the GitHub GraphQL API.
+type graphqlClient struct {
+	http  *http.Client
+	url   string
+	token string
+}
+
+func newGraphQLClient(token, baseURL string) *graphqlClient {
+	url := "https://api.github.com/graphql"
+	if baseURL != "" {
+		url = webBaseURL(baseURL) + "/api/graphql"
+	}
+	return &graphqlClient{http: http.DefaultClient, url: url, token: token}
+}
+
+// query runs a GraphQL query and decodes its data into v.
+func (c *graphqlClient) query(ctx context.Context, query string, vars map[string]any, v any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("POST %s: %s", c.url, resp.Status)
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		msgs := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			msgs[i] = e.Message
+		}
+		return fmt.Errorf("graphql: %s", strings.Join(msgs, "; "))
+	}
+	return json.Unmarshal(result.Data, v)
+}
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type repoRef struct {
+	NameWithOwner string `json:"nameWithOwner"`
+}
+
+type issueRef struct {
+	Number     int       `json:"number"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	Repository repoRef   `json:"repository"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// pullRequestFields selects what pullRequestRef needs to link a pull
+// request's commits to it.
+const pullRequestFields = `headRefName headRepository { nameWithOwner } merged mergeCommit { oid } commits(first: 100) { nodes { commit { oid } } }`
+
+type pullRequestRef struct {
+	issueRef
+	HeadRefName    string   `json:"headRefName"`
+	HeadRepository *repoRef `json:"headRepository"`
+	Merged         bool     `json:"merged"`
+	MergeCommit    *struct {
+		OID string `json:"oid"`
+	} `json:"mergeCommit"`
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				OID string `json:"oid"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+}
+
+// link sets the source branch and commit SHAs of the pull request on e. As
+// in the REST backend, the branch is only set for pull requests from the
+// same repository.
+func (pr pullRequestRef) link(e report.Event) report.Event {
+	if pr.HeadRepository != nil && pr.HeadRepository.NameWithOwner == e.Repo {
+		e.Branch = pr.HeadRefName
+	}
+	for _, c := range pr.Commits.Nodes {
+		e.CommitSHAs = append(e.CommitSHAs, c.Commit.OID)
+	}
+	if pr.Merged && pr.MergeCommit != nil {
+		e.CommitSHAs = append(e.CommitSHAs, pr.MergeCommit.OID)
+	}
+	return e
+}
+
+// contributionsCollection spans at most a year and each repository's
+// contributions are read one page at a time, so the range is queried in
+// windows that are halved while a repository has more than a page or the
+// user contributed to more repositories than are listed.
+const contributionsWindow = 30 * 24 * time.Hour
+
+const contributionsQuery = `query($from: DateTime!, $to: DateTime!) {
+  viewer {
+    contributionsCollection(from: $from, to: $to) {
+      totalRepositoriesWithContributedCommits
+      totalRepositoriesWithContributedIssues
+      totalRepositoriesWithContributedPullRequests
+      totalRepositoriesWithContributedPullRequestReviews
+      commitContributionsByRepository(maxRepositories: 100) {
+        repository { nameWithOwner }
+      }
+      issueContributionsByRepository(maxRepositories: 100) {
+        repository { nameWithOwner }
+        contributions(first: 100) {
+          pageInfo { hasNextPage }
+          nodes { occurredAt issue { number title url } }
+        }
+      }
+      pullRequestContributionsByRepository(maxRepositories: 100) {
+        repository { nameWithOwner }
+        contributions(first: 100) {
+          pageInfo { hasNextPage }
+          nodes { occurredAt pullRequest { number title url ` + pullRequestFields + ` } }
+        }
+      }
+      pullRequestReviewContributionsByRepository(maxRepositories: 100) {
+        repository { nameWithOwner }
+        contributions(first: 100) {
+          pageInfo { hasNextPage }
+          nodes {
+            occurredAt
+            pullRequest { number title }
+            pullRequestReview {
+              state
+              url
+              comments(first: 100) { nodes { createdAt url } }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type contributions struct {
+	Viewer struct {
+		ContributionsCollection struct {
+			TotalRepositoriesWithContributedCommits            int `json:"totalRepositoriesWithContributedCommits"`
+			TotalRepositoriesWithContributedIssues             int `json:"totalRepositoriesWithContributedIssues"`
+			TotalRepositoriesWithContributedPullRequests       int `json:"totalRepositoriesWithContributedPullRequests"`
+			TotalRepositoriesWithContributedPullRequestReviews int `json:"totalRepositoriesWithContributedPullRequestReviews"`
+			CommitContributionsByRepository                    []struct {
+				Repository repoRef `json:"repository"`
+			} `json:"commitContributionsByRepository"`
+			IssueContributionsByRepository []struct {
+				Repository    repoRef `json:"repository"`
+				Contributions struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						OccurredAt time.Time `json:"occurredAt"`
+						Issue      issueRef  `json:"issue"`
+					} `json:"nodes"`
+				} `json:"contributions"`
+			} `json:"issueContributionsByRepository"`
+			PullRequestContributionsByRepository []struct {
+				Repository    repoRef `json:"repository"`
+				Contributions struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						OccurredAt  time.Time      `json:"occurredAt"`
+						PullRequest pullRequestRef `json:"pullRequest"`
+					} `json:"nodes"`
+				} `json:"contributions"`
+			} `json:"pullRequestContributionsByRepository"`
+			PullRequestReviewContributionsByRepository []struct {
+				Repository    repoRef `json:"repository"`
+				Contributions struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						OccurredAt        time.Time `json:"occurredAt"`
+						PullRequest       issueRef  `json:"pullRequest"`
+						PullRequestReview struct {
+							State    string `json:"state"`
+							URL      string `json:"url"`
+							Comments struct {
+								Nodes []struct {
+									CreatedAt time.Time `json:"createdAt"`
+									URL       string    `json:"url"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"pullRequestReview"`
+					} `json:"nodes"`
+				} `json:"contributions"`
+			} `json:"pullRequestReviewContributionsByRepository"`
+		} `json:"contributionsCollection"`
+	} `json:"viewer"`
+}
+
+// truncated reports whether the user contributed to more repositories, or
+// any repository has more contributions of a kind, than the query returned.
+func (c *contributions) truncated() bool {
+	cc := c.Viewer.ContributionsCollection
+	if len(cc.CommitContributionsByRepository) < cc.TotalRepositoriesWithContributedCommits ||
+		len(cc.IssueContributionsByRepository) < cc.TotalRepositoriesWithContributedIssues ||
+		len(cc.PullRequestContributionsByRepository) < cc.TotalRepositoriesWithContributedPullRequests ||
+		len(cc.PullRequestReviewContributionsByRepository) < cc.TotalRepositoriesWithContributedPullRequestReviews {
+		return true
+	}
+	for _, r := range cc.IssueContributionsByRepository {
+		if r.Contributions.PageInfo.HasNextPage {
+			return true
+		}
+	}
+	for _, r := range cc.PullRequestContributionsByRepository {
+		if r.Contributions.PageInfo.HasNextPage {
+			return true
+		}
+	}
+	for _, r := range cc.PullRequestReviewContributionsByRepository {
+		if r.Contributions.PageInfo.HasNextPage {
+			return true
+		}
+	}
+	return false
+}
+
+// graphqlFetcher collects the user's activity from the GraphQL API. It is
+// an alternative to the REST activity feed that needs no per-repo calls for
+// CI failures and is not limited to the last 90 days.
+type graphqlFetcher struct {
+	c      *graphqlClient
+	filter report.RepoFilter
+	since  time.Time
+	until  time.Time
+
+	viewerID string
+	login    string
+	repos    map[string]bool // repos the user committed to or opened pull requests in
+
+	events   []report.Event
+	warnings []string
+}
+
+func (f *graphqlFetcher) warnf(format string, args ...any) {
+	f.warnings = append(f.warnings, fmt.Sprintf(format, args...))
+}
+
+// add records an event if it falls in the range and its repo passes the
+// filter.
+func (f *graphqlFetcher) add(e report.Event) {
+	if e.CreatedAt.Before(f.since) || e.CreatedAt.After(f.until) || !f.filter.Match(e.Repo) {
+		return
+	}
+	e.Source = "github"
+	f.events = append(f.events, e)
+}
+
+func fetchGraphQL(ctx context.Context, token, baseURL string, filter report.RepoFilter, since, until time.Time) ([]report.Event, []string, error) {
+	f := &graphqlFetcher{
+		c:      newGraphQLClient(token, baseURL),
+		filter: filter,
+		since:  since,
+		until:  until,
+		repos:  make(map[string]bool),
+	}
+
+	var viewer struct {
+		Viewer struct {
+			ID    string `json:"id"`
+			Login string `json:"login"`
+		} `json:"viewer"`
+	}
+	if err := f.c.query(ctx, `query { viewer { id login } }`, nil, &viewer); err != nil {
+		return nil, nil, fmt.Errorf("getting user: %w", err)
+	}
+	f.viewerID, f.login = viewer.Viewer.ID, viewer.Viewer.Login
+
+	for from := since; from.Before(until); from = from.Add(contributionsWindow) {
+		to := from.Add(contributionsWindow)
+		if to.After(until) {
+			to = until
+		}
+		if err := f.contributions(ctx, from, to); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, step := range []struct {
+		what string
+		fn   func(context.Context) error
+	}{
+		{"commits and CI failures", f.commitHistory},
+		{"closed pull requests", f.closedPullRequests},
+		{"comments", f.issueComments},
+		{"pending reviews", f.pendingReviews},
+	} {
+		if err := step.fn(ctx); err != nil {
+			f.warnf("could not read %s: %v", step.what, err)
+		}
+	}
+	return f.events, f.warnings, nil
+}
+
+// contributions adds the pull requests and issues the user opened and the
+// reviews they submitted between from and to, and notes the repos they
+// committed to or opened pull requests in.
+func (f *graphqlFetcher) contributions(ctx context.Context, from, to time.Time) error {
+	var result contributions
+	vars := map[string]any{"from": from.UTC().Format(time.RFC3339), "to": to.UTC().Format(time.RFC3339)}
+	if err := f.c.query(ctx, contributionsQuery, vars, &result); err != nil {
+		return err
+	}
+	if result.truncated() {
+		if span := to.Sub(from); span > 24*time.Hour {
+			mid := from.Add(span / 2)
+			if err := f.contributions(ctx, from, mid); err != nil {
+				return err
+			}
+			return f.contributions(ctx, mid, to)
+		}
+		f.warnf("more than 100 repositories or more than 100 contributions to a repository on %s; some are missing", from.Format("Jan 2"))
+	}
+
+	cc := result.Viewer.ContributionsCollection
+	for _, r := range cc.CommitContributionsByRepository {
+		f.repos[r.Repository.NameWithOwner] = true
+	}
+	for _, r := range cc.PullRequestContributionsByRepository {
+		f.repos[r.Repository.NameWithOwner] = true
+		for _, n := range r.Contributions.Nodes {
+			f.add(n.PullRequest.link(report.Event{
+				Category:  report.CategoryPR,
+				Action:    "opened",
+				Title:     fmt.Sprintf("#%d %s", n.PullRequest.Number, n.PullRequest.Title),
+				URL:       n.PullRequest.URL,
+				Repo:      r.Repository.NameWithOwner,
+				CreatedAt: n.OccurredAt,
+			}))
+		}
+	}
+	for _, r := range cc.IssueContributionsByRepository {
+		for _, n := range r.Contributions.Nodes {
+			f.add(report.Event{
+				Category:  report.CategoryIssue,
+				Action:    "opened",
+				Title:     fmt.Sprintf("#%d %s", n.Issue.Number, n.Issue.Title),
+				URL:       n.Issue.URL,
+				Repo:      r.Repository.NameWithOwner,
+				CreatedAt: n.OccurredAt,
+			})
+		}
+	}
+	for _, r := range cc.PullRequestReviewContributionsByRepository {
+		for _, n := range r.Contributions.Nodes {
+			title := fmt.Sprintf("#%d %s", n.PullRequest.Number, n.PullRequest.Title)
+			f.add(report.Event{
+				Category:  report.CategoryReview,
+				Action:    reviewAction(n.PullRequestReview.State),
+				Title:     title,
+				URL:       n.PullRequestReview.URL,
+				Repo:      r.Repository.NameWithOwner,
+				CreatedAt: n.OccurredAt,
+			})
+			for _, c := range n.PullRequestReview.Comments.Nodes {
+				f.add(report.Event{
+					Category:  report.CategoryReviewComment,
+					Action:    "commented",
+					Title:     title,
+					URL:       c.URL,
+					Repo:      r.Repository.NameWithOwner,
+					CreatedAt: c.CreatedAt,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// historyBranches is how many branches of a repo are read for commits, most
+// recently committed to first.
+const historyBranches = 50
+
+// historyFields selects a page of a branch history with the check suites
+// that ran on each commit.
+const historyFields = `pageInfo { hasNextPage endCursor }
+          nodes {
+            oid messageHeadline url authoredDate
+            checkSuites(first: 10) { nodes { conclusion branch { name } workflowRun { url createdAt workflow { name } } } }
+          }`
+
+type historyCommit struct {
+	OID             string    `json:"oid"`
+	MessageHeadline string    `json:"messageHeadline"`
+	URL             string    `json:"url"`
+	AuthoredDate    time.Time `json:"authoredDate"`
+	CheckSuites     struct {
+		Nodes []struct {
+			Conclusion string `json:"conclusion"`
+			Branch     *struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			WorkflowRun *struct {
+				URL       string    `json:"url"`
+				CreatedAt time.Time `json:"createdAt"`
+				Workflow  struct {
+					Name string `json:"name"`
+				} `json:"workflow"`
+			} `json:"workflowRun"`
+		} `json:"nodes"`
+	} `json:"checkSuites"`
+}
+
+type commitHistory struct {
+	PageInfo pageInfo        `json:"pageInfo"`
+	Nodes    []historyCommit `json:"nodes"`
+}
+
+// commitHistory adds the user's commits on the branches of each repo they
+// committed to or opened a pull request in, and the failed workflow runs of
+// those commits. The repos are queried in batches, one alias per repo.
+func (f *graphqlFetcher) commitHistory(ctx context.Context) error {
+	var repos []string
+	for repo := range f.repos {
+		if f.filter.Match(repo) {
+			repos = append(repos, repo)
+		}
+	}
+	slices.Sort(repos)
+
+	const batch = 5
+	for start := 0; start < len(repos); start += batch {
+		end := min(start+batch, len(repos))
+		var q strings.Builder
+		q.WriteString("query($author: ID!, $since: GitTimestamp!, $until: GitTimestamp!) {\n")
+		for i, repo := range repos[start:end] {
+			owner, name, _ := strings.Cut(repo, "/")
+			fmt.Fprintf(&q, `  r%d: repository(owner: %q, name: %q) {
+    nameWithOwner
+    defaultBranchRef { name }
+    refs(refPrefix: "refs/heads/", first: %d, orderBy: {field: TAG_COMMIT_DATE, direction: DESC}) {
+      pageInfo { hasNextPage }
+      nodes { name target { ... on Commit {
+        committedDate
+        history(first: 100, author: {id: $author}, since: $since, until: $until) {
+          %s
+        }
+      } } }
+    }
+  }
+`, i, owner, name, historyBranches, historyFields)
+		}
+		q.WriteString("}")
+
+		var result map[string]*struct {
+			NameWithOwner    string `json:"nameWithOwner"`
+			DefaultBranchRef *struct {
+				Name string `json:"name"`
+			} `json:"defaultBranchRef"`
+			Refs struct {
+				PageInfo pageInfo `json:"pageInfo"`
+				Nodes    []struct {
+					Name   string `json:"name"`
+					Target struct {
+						CommittedDate time.Time     `json:"committedDate"`
+						History       commitHistory `json:"history"`
+					} `json:"target"`
+				} `json:"nodes"`
+			} `json:"refs"`
+		}
+		if err := f.c.query(ctx, q.String(), f.historyVars(), &result); err != nil {
+			return err
+		}
+
+		for _, r := range result {
+			if r == nil {
+				continue
+			}
+			refs := r.Refs.Nodes
+			if r.Refs.PageInfo.HasNextPage && len(refs) > 0 && !refs[len(refs)-1].Target.CommittedDate.Before(f.since) {
+				f.warnf("more than %d branches of %s were committed to in the range; commits on the others are missing", historyBranches, r.NameWithOwner)
+			}
+			defaultBranch := ""
+			if r.DefaultBranchRef != nil {
+				defaultBranch = r.DefaultBranchRef.Name
+			}
+			// A commit on several branches is reported once. The other
+			// branches are read before the default one, so a commit merged
+			// into it is reported on the branch it was pushed to, as the
+			// activity feed does.
+			seen := make(map[string]bool)
+			for _, onDefault := range []bool{false, true} {
+				for _, ref := range refs {
+					if (ref.Name == defaultBranch) != onDefault {
+						continue
+					}
+					commits := ref.Target.History.Nodes
+					if page := ref.Target.History.PageInfo; page.HasNextPage {
+						more, err := f.moreHistory(ctx, r.NameWithOwner, ref.Name, page.EndCursor)
+						if err != nil {
+							return err
+						}
+						commits = append(commits, more...)
+					}
+					for _, c := range commits {
+						if !seen[c.OID] {
+							seen[c.OID] = true
+							f.addCommit(r.NameWithOwner, ref.Name, c)
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// moreHistory returns the user's commits on a branch after the cursor, page
+// by page.
+func (f *graphqlFetcher) moreHistory(ctx context.Context, repo, branch, after string) ([]historyCommit, error) {
+	const query = `query($owner: String!, $name: String!, $ref: String!, $after: String, $author: ID!, $since: GitTimestamp!, $until: GitTimestamp!) {
+  repository(owner: $owner, name: $name) {
+    ref(qualifiedName: $ref) { target { ... on Commit {
+      history(first: 100, after: $after, author: {id: $author}, since: $since, until: $until) {
+          ` + historyFields + `
+      }
+    } } }
+  }
+}`
+	owner, name, _ := strings.Cut(repo, "/")
+	vars := f.historyVars()
+	vars["owner"], vars["name"], vars["ref"] = owner, name, "refs/heads/"+branch
+
+	var commits []historyCommit
+	for {
+		vars["after"] = after
+		var result struct {
+			Repository struct {
+				Ref *struct {
+					Target struct {
+						History commitHistory `json:"history"`
+					} `json:"target"`
+				} `json:"ref"`
+			} `json:"repository"`
+		}
+		if err := f.c.query(ctx, query, vars, &result); err != nil {
+			return nil, err
+		}
+		if result.Repository.Ref == nil {
+			return commits, nil
+		}
+		history := result.Repository.Ref.Target.History
+		commits = append(commits, history.Nodes...)
+		if !history.PageInfo.HasNextPage {
+			return commits, nil
+		}
+		after = history.PageInfo.EndCursor
+	}
+}
+
+func (f *graphqlFetcher) historyVars() map[string]any {
+	return map[string]any{
+		"author": f.viewerID,
+		"since":  f.since.UTC().Format(time.RFC3339),
+		"until":  f.until.UTC().Format(time.RFC3339),
+	}
+}
+
+// addCommit adds a commit pushed to a branch and its failed workflow runs.
+func (f *graphqlFetcher) addCommit(repo, branch string, c historyCommit) {
+	f.add(report.Event{
+		Category:  report.CategoryCommit,
+		Action:    "pushed",
+		Title:     c.MessageHeadline,
+		URL:       c.URL,
+		Repo:      repo,
+		SHA:       c.OID,
+		Branch:    branch,
+		CreatedAt: c.AuthoredDate,
+	})
+	for _, s := range c.CheckSuites.Nodes {
+		if s.Conclusion != "FAILURE" || s.WorkflowRun == nil {
+			continue
+		}
+		runBranch := ""
+		if s.Branch != nil {
+			runBranch = s.Branch.Name
+		}
+		f.add(report.Event{
+			Category:  report.CategoryPipeline,
+			Action:    "failed",
+			Title:     fmt.Sprintf("%s on %s", s.WorkflowRun.Workflow.Name, runBranch),
+			URL:       s.WorkflowRun.URL,
+			Repo:      repo,
+			CreatedAt: s.WorkflowRun.CreatedAt,
+		})
+	}
+}
+
+// closedPullRequests adds the user's pull requests that were merged or
+// closed in the range, newest first until the range is passed.
+func (f *graphqlFetcher) closedPullRequests(ctx context.Context) error {
+	const query = `query($after: String) {
+  viewer {
+    pullRequests(first: 100, after: $after, states: [MERGED, CLOSED], orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes { number title url mergedAt closedAt updatedAt repository { nameWithOwner } ` + pullRequestFields + ` }
+    }
+  }
+}`
+	var after any
+	for {
+		var result struct {
+			Viewer struct {
+				PullRequests struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						pullRequestRef
+						MergedAt  time.Time `json:"mergedAt"`
+						ClosedAt  time.Time `json:"closedAt"`
+						UpdatedAt time.Time `json:"updatedAt"`
+					} `json:"nodes"`
+				} `json:"pullRequests"`
+			} `json:"viewer"`
+		}
+		if err := f.c.query(ctx, query, map[string]any{"after": after}, &result); err != nil {
+			return err
+		}
+		prs := result.Viewer.PullRequests
+		for _, pr := range prs.Nodes {
+			if pr.UpdatedAt.Before(f.since) {
+				return nil
+			}
+			action, at := "closed", pr.ClosedAt
+			if pr.Merged {
+				action, at = "merged", pr.MergedAt
+			}
+			f.add(pr.link(report.Event{
+				Category:  report.CategoryPR,
+				Action:    action,
+				Title:     fmt.Sprintf("#%d %s", pr.Number, pr.Title),
+				URL:       pr.URL,
+				Repo:      pr.Repository.NameWithOwner,
+				CreatedAt: at,
+			}))
+		}
+		if !prs.PageInfo.HasNextPage {
+			return nil
+		}
+		after = prs.PageInfo.EndCursor
+	}
+}
+
+// issueComments adds the user's comments on issues and pull requests,
+// newest first until the range is passed.
+func (f *graphqlFetcher) issueComments(ctx context.Context) error {
+	const query = `query($after: String) {
+  viewer {
+    issueComments(first: 100, after: $after, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes { createdAt updatedAt url issue { number title repository { nameWithOwner } } }
+    }
+  }
+}`
+	var after any
+	for {
+		var result struct {
+			Viewer struct {
+				IssueComments struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						CreatedAt time.Time `json:"createdAt"`
+						UpdatedAt time.Time `json:"updatedAt"`
+						URL       string    `json:"url"`
+						Issue     issueRef  `json:"issue"`
+					} `json:"nodes"`
+				} `json:"issueComments"`
+			} `json:"viewer"`
+		}
+		if err := f.c.query(ctx, query, map[string]any{"after": after}, &result); err != nil {
+			return err
+		}
+		comments := result.Viewer.IssueComments
+		for _, c := range comments.Nodes {
+			if c.UpdatedAt.Before(f.since) {
+				return nil
+			}
+			f.add(report.Event{
+				Category:  report.CategoryComment,
+				Action:    "commented",
+				Title:     fmt.Sprintf("#%d %s", c.Issue.Number, c.Issue.Title),
+				URL:       c.URL,
+				Repo:      c.Issue.Repository.NameWithOwner,
+				CreatedAt: c.CreatedAt,
+			})
+		}
+		if !comments.PageInfo.HasNextPage {
+			return nil
+		}
+		after = comments.PageInfo.EndCursor
+	}
+}
+
+// pendingReviews adds the open pull requests awaiting the user's review.
+// They describe the present, so they are not limited to the range.
+func (f *graphqlFetcher) pendingReviews(ctx context.Context) error {
+	const query = `query($q: String!) {
+  search(query: $q, type: ISSUE, first: 100) {
+    nodes { ... on PullRequest { number title url createdAt repository { nameWithOwner } } }
+  }
+}`
+	var result struct {
+		Search struct {
+			Nodes []issueRef `json:"nodes"`
+		} `json:"search"`
+	}
+	q := fmt.Sprintf("is:pr is:open review-requested:%s", f.login) + searchQualifiers(f.filter)
+	if err := f.c.query(ctx, query, map[string]any{"q": q}, &result); err != nil {
+		return err
+	}
+	for _, pr := range result.Search.Nodes {
+		if !f.filter.Match(pr.Repository.NameWithOwner) {
+			continue
+		}
+		f.events = append(f.events, report.Event{
+			Category:  report.CategoryPendingReview,
+			Action:    "awaiting your review",
+			Title:     fmt.Sprintf("#%d %s", pr.Number, pr.Title),
+			URL:       pr.URL,
+			Repo:      pr.Repository.NameWithOwner,
+			Source:    "github",
+			CreatedAt: pr.CreatedAt,
+		})
+	}
+	return nil
+}
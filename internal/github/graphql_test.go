@@ -0,0 +1,127 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"worklog/internal/report"
+	"worklog/internal/testutil"
+)
+
+// graphqlFixtures maps a fragment of each query the GraphQL backend sends to
+// the key of its response in testdata/graphql.json, most specific first.
+var graphqlFixtures = []struct{ fragment, key string }{
+	{"contributionsCollection", "contributions"},
+	{"ref(qualifiedName:", "moreHistory"},
+	{"history(", "history"},
+	{"pullRequests(first", "closedPullRequests"},
+	{"issueComments(", "issueComments"},
+	{"search(", "search"},
+	{"viewer { id login }", "viewer"},
+}
+
+// newEnterpriseServer serves the REST API responses in testdata/rest.json
+// under /api/v3, keyed by path, and the GraphQL responses in
+// testdata/graphql.json at /api/graphql, as a GitHub Enterprise Server
+// would. Both files record the same activity. "{{server}}" in them is
+// replaced with the server's URL.
+func newEnterpriseServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var rest, graphql map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Header.Get("Authorization"), "bearer token") {
+			http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/graphql" {
+			var req struct {
+				Query string `json:"query"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("decoding GraphQL request: %v", err)
+			}
+			for _, f := range graphqlFixtures {
+				if strings.Contains(req.Query, f.fragment) {
+					fmt.Fprintf(w, `{"data": %s}`, graphql[f.key])
+					return
+				}
+			}
+			t.Errorf("unexpected GraphQL query:\n%s", req.Query)
+			http.Error(w, `{"message": "unexpected query"}`, http.StatusBadRequest)
+			return
+		}
+		body, ok := rest[strings.TrimPrefix(r.URL.Path, "/api/v3")]
+		if !ok {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	rest = testutil.Fixtures(t, "testdata/rest.json", srv.URL)
+	graphql = testutil.Fixtures(t, "testdata/graphql.json", srv.URL)
+	return srv
+}
+
+func fetchBackend(t *testing.T, baseURL, backend string) ([]report.Event, []string) {
+	t.Helper()
+	p := &Provider{}
+	settings := map[string]string{"TOKEN": "token", "URL": baseURL, "BACKEND": backend}
+	if _, err := p.Configure(func(key string) string { return settings[key] }); err != nil {
+		t.Fatal(err)
+	}
+	events, err := p.FetchEvents(context.Background(), testutil.Since, testutil.Until)
+	if err != nil {
+		t.Fatalf("%s backend: %v", backend, err)
+	}
+	return events, p.Warnings()
+}
+
+func TestBackendParity(t *testing.T) {
+	srv := newEnterpriseServer(t)
+	restEvents, restWarnings := fetchBackend(t, srv.URL, "rest")
+	graphqlEvents, graphqlWarnings := fetchBackend(t, srv.URL, "graphql")
+
+	want := []string{
+		`Pull Requests / Merge Requests | opened | #7 Add caching | acme/api | https://ghe.example.com/acme/api/pull/7 | github | 2026-10-13T09:00:00Z | branch "caching" commits [c1 c2 m7]`,
+		`Pull Requests / Merge Requests | merged | #7 Add caching | acme/api | https://ghe.example.com/acme/api/pull/7 | github | 2026-10-14T15:30:00Z | branch "caching" commits [c1 c2 m7]`,
+		`Code Reviews | approved | #8 Fix login | acme/web | https://ghe.example.com/acme/web/pull/8#pullrequestreview-1 | github | 2026-10-15T11:00:00Z`,
+		`Review Comments | commented | #8 Fix login | acme/web | https://ghe.example.com/acme/web/pull/8#discussion_r2 | github | 2026-10-15T11:00:00Z`,
+		`Issues | opened | #3 Crash on start | acme/api | https://ghe.example.com/acme/api/issues/3 | github | 2026-10-12T08:00:00Z`,
+		`Comments | commented | #3 Crash on start | acme/api | https://ghe.example.com/acme/api/issues/3#issuecomment-5 | github | 2026-10-14T09:00:00Z`,
+		`Commits | pushed | Fix typo in README | acme/api | ` + srv.URL + `/acme/api/commit/c3 | github | 2026-10-13T10:00:00Z | sha c3 | branch "main" commits []`,
+		`Commits | pushed | Add cache store | acme/api | ` + srv.URL + `/acme/api/commit/c1 | github | 2026-10-13T08:30:00Z | sha c1 | branch "caching" commits []`,
+		`Commits | pushed | Cache lookups | acme/api | ` + srv.URL + `/acme/api/commit/c2 | github | 2026-10-13T08:45:00Z | sha c2 | branch "caching" commits []`,
+		`CI Pipeline Failures | failed | CI on main | acme/api | https://ghe.example.com/acme/api/actions/runs/11 | github | 2026-10-13T10:05:00Z`,
+		`Pending Reviews | awaiting your review | #9 Update docs | acme/web | https://ghe.example.com/acme/web/pull/9 | github | 2026-10-09T12:00:00Z`,
+	}
+	for backend, events := range map[string][]report.Event{"rest": restEvents, "graphql": graphqlEvents} {
+		t.Run(backend, func(t *testing.T) {
+			testutil.CheckEvents(t, events, want)
+		})
+	}
+	if len(restWarnings) != 0 || len(graphqlWarnings) != 0 {
+		t.Errorf("got warnings %q from REST and %q from GraphQL, want none", restWarnings, graphqlWarnings)
+	}
+}
+
+func TestBackendAuthError(t *testing.T) {
+	srv := newEnterpriseServer(t)
+	for _, backend := range []string{"rest", "graphql"} {
+		p := &Provider{}
+		settings := map[string]string{"TOKEN": "wrong", "URL": srv.URL, "BACKEND": backend}
+		if _, err := p.Configure(func(key string) string { return settings[key] }); err != nil {
+			t.Fatal(err)
+		}
+		_, err := p.FetchEvents(context.Background(), testutil.Since, testutil.Until)
+		if err == nil || !strings.HasPrefix(err.Error(), "getting user: ") || !strings.Contains(err.Error(), "401") {
+			t.Errorf("%s backend: got error %v, want a 401 getting the user", backend, err)
+		}
+	}
+}
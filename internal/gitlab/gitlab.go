@@ -10,51 +10,93 @@ import (
 	"time"
 
 	gl "gitlab.com/gitlab-org/api/client-go"
+	"worklog/internal/provider"
 	"worklog/internal/report"
 )
 
-func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]report.Event, error) {
-	client, err := newClient(token)
+func init() {
+	provider.Register("gitlab", func() provider.Provider { return &Provider{} })
+}
+
+// Provider reports activity for the GitLab user owning GITLAB_TOKEN on
+// gitlab.com, or on the instance at GITLAB_URL when set.
+type Provider struct {
+	token    string
+	baseURL  string
+	filter   report.RepoFilter
+	warnings []string
+}
+
+func (p *Provider) Name() string { return "gitlab" }
+
+func (p *Provider) Configure(lookup func(string) string) (bool, error) {
+	p.token = lookup("TOKEN")
+	p.baseURL = lookup("URL")
+	return p.token != "", nil
+}
+
+func (p *Provider) Identity() string { return p.baseURL + "\x00" + p.token }
+
+func (p *Provider) SetRepoFilter(f report.RepoFilter) { p.filter = f }
+
+func (p *Provider) FetchEvents(ctx context.Context, since, until time.Time) ([]report.Event, error) {
+	events, warnings, err := FetchEvents(ctx, p.token, p.baseURL, p.filter, since, until)
+	p.warnings = warnings
+	return events, err
+}
+
+// Warnings returns the gaps in coverage found by the last FetchEvents, such
+// as events beyond the number it reads.
+func (p *Provider) Warnings() []string { return p.warnings }
+
+// FetchEvents returns the user's activity between since and until, and
+// warnings about activity it could not read.
+func FetchEvents(ctx context.Context, token, baseURL string, filter report.RepoFilter, since, until time.Time) ([]report.Event, []string, error) {
+	client, err := newClient(token, baseURL)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	u, _, err := client.Users.CurrentUser(gl.WithContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("getting user: %w", err)
+		return nil, nil, fmt.Errorf("getting user: %w", err)
 	}
 
 	projectCache := make(map[int64]*gl.Project)
 	projectIDs := make(map[int64]struct{})
 	var events []report.Event
 
-	afterTime := gl.ISOTime(since)
-	beforeTime := gl.ISOTime(until.AddDate(0, 0, 1))
+	// The events API takes exclusive dates in UTC, so the query covers the
+	// whole days around the range and the events are filtered exactly.
+	afterTime := gl.ISOTime(since.UTC().AddDate(0, 0, -1))
+	beforeTime := gl.ISOTime(until.UTC().AddDate(0, 0, 1))
 
-	for page := int64(1); page <= 100; page++ {
-		opts := &gl.ListContributionEventsOptions{
-			After:       &afterTime,
-			Before:      &beforeTime,
-			ListOptions: gl.ListOptions{PerPage: 100, Page: page},
-		}
-		glEvents, resp, err := client.Events.ListCurrentUserContributionEvents(opts, gl.WithContext(ctx))
-		if err != nil {
-			return nil, err
+	glEvents, warnings, err := listEvents(ctx, client, afterTime, beforeTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pushErr error
+	for _, e := range glEvents {
+		proj, err := resolveProject(ctx, client, e.ProjectID, projectCache)
+		if err != nil || !filter.Match(proj.PathWithNamespace) {
+			continue
 		}
-		if len(glEvents) == 0 {
-			break
+		if e.CreatedAt == nil || e.CreatedAt.Before(since) || e.CreatedAt.After(until) {
+			continue
 		}
-		for _, e := range glEvents {
-			proj, err := resolveProject(ctx, client, e.ProjectID, projectCache)
-			if err != nil {
-				continue
+		projectIDs[e.ProjectID] = struct{}{}
+		parsed := parseEvent(e, proj)
+		if len(parsed) == 1 && e.PushData.CommitCount > 1 {
+			if commits, err := pushCommits(ctx, client, e); err != nil {
+				pushErr = err
+			} else if len(commits) > 0 {
+				parsed = commitEvents(parsed[0], commits, proj)
 			}
-			projectIDs[e.ProjectID] = struct{}{}
-			events = append(events, parseEvent(e, proj)...)
-		}
-		if resp.NextPage == 0 {
-			break
 		}
+		events = append(events, parsed...)
+	}
+	if pushErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: gitlab pushed commits: %v\n", pushErr)
 	}
 
 	// Phase 2: Fetch CI failures and pending reviews in parallel.
@@ -80,7 +122,7 @@ func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]r
 
 	go func() {
 		defer wg.Done()
-		prEvents, err := fetchPendingReviews(ctx, client, u.ID, cacheSnapshot)
+		prEvents, err := fetchPendingReviews(ctx, client, u.ID, filter, cacheSnapshot)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "warning: gitlab pending reviews: %v\n", err)
 			return
@@ -91,7 +133,107 @@ func FetchEvents(ctx context.Context, token string, since, until time.Time) ([]r
 	}()
 
 	wg.Wait()
-	return events, nil
+
+	err = report.LinkPullRequests(events, func(e report.Event) (report.PullRequestCommits, error) {
+		return readMergeRequest(ctx, client, e)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: gitlab merge request commits: %v\n", err)
+	}
+	return events, warnings, nil
+}
+
+// listEvents lists the user's contribution events between the exclusive
+// dates after and before, and a warning when there were more than it reads.
+// GitLab cannot scope the user's events to a group or project, so the repo
+// filter is applied to them by the caller.
+func listEvents(ctx context.Context, client *gl.Client, after, before gl.ISOTime) ([]*gl.ContributionEvent, []string, error) {
+	const maxPages = 100
+	var events []*gl.ContributionEvent
+	for page := int64(1); page <= maxPages; page++ {
+		opts := &gl.ListContributionEventsOptions{
+			After:       &after,
+			Before:      &before,
+			ListOptions: gl.ListOptions{PerPage: 100, Page: page},
+		}
+		pageEvents, resp, err := client.Events.ListCurrentUserContributionEvents(opts, gl.WithContext(ctx))
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, pageEvents...)
+		if len(pageEvents) == 0 || resp.NextPage == 0 {
+			return events, nil, nil
+		}
+	}
+	warning := fmt.Sprintf("only the %d most recent events were read; earlier activity is missing", len(events))
+	return events, []string{warning}, nil
+}
+
+// pushCommits lists the commits of a push event, which only names the head
+// commit. A push to an existing branch is compared with the branch's
+// previous head; for a new branch, the pushed number of commits is read back
+// from the head.
+func pushCommits(ctx context.Context, client *gl.Client, e *gl.ContributionEvent) ([]*gl.Commit, error) {
+	pd := e.PushData
+	if strings.Trim(pd.CommitFrom, "0") != "" {
+		cmp, _, err := client.Repositories.Compare(e.ProjectID,
+			&gl.CompareOptions{From: new(pd.CommitFrom), To: new(pd.CommitTo)}, gl.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return cmp.Commits, nil
+	}
+	commits, _, err := client.Commits.ListCommits(e.ProjectID, &gl.ListCommitsOptions{
+		RefName:     new(pd.CommitTo),
+		ListOptions: gl.ListOptions{PerPage: min(pd.CommitCount, 100)},
+	}, gl.WithContext(ctx))
+	return commits, err
+}
+
+// commitEvents turns the head commit event of a push into an event for each
+// of the pushed commits, so that each can be deduplicated against local
+// commits and grouped under its merge request.
+func commitEvents(head report.Event, commits []*gl.Commit, proj *gl.Project) []report.Event {
+	events := make([]report.Event, len(commits))
+	for i, c := range commits {
+		e := head
+		e.Title = c.Title
+		e.SHA = c.ID
+		e.URL = fmt.Sprintf("%s/-/commit/%s", proj.WebURL, c.ID)
+		events[i] = e
+	}
+	return events
+}
+
+// readMergeRequest reads the source branch and commits of the merge request
+// of e for report.LinkPullRequests.
+func readMergeRequest(ctx context.Context, client *gl.Client, e report.Event) (report.PullRequestCommits, error) {
+	var mr report.PullRequestCommits
+	var iid int64
+	if _, err := fmt.Sscanf(e.Title, "!%d", &iid); err != nil {
+		return mr, err
+	}
+	m, _, err := client.MergeRequests.GetMergeRequest(e.Repo, iid, nil, gl.WithContext(ctx))
+	if err != nil {
+		return mr, err
+	}
+	commits, _, err := client.MergeRequests.GetMergeRequestCommits(e.Repo, iid,
+		&gl.GetMergeRequestCommitsOptions{ListOptions: gl.ListOptions{PerPage: 100}}, gl.WithContext(ctx))
+	if err != nil {
+		return mr, err
+	}
+	if m.SourceProjectID == m.TargetProjectID {
+		mr.Branch = m.SourceBranch
+	}
+	for _, c := range commits {
+		mr.SHAs = append(mr.SHAs, c.ID)
+	}
+	for _, sha := range []string{m.MergeCommitSHA, m.SquashCommitSHA} {
+		if sha != "" {
+			mr.SHAs = append(mr.SHAs, sha)
+		}
+	}
+	return mr, nil
 }
 
 func fetchCIFailures(ctx context.Context, client *gl.Client, username string, projectIDs map[int64]struct{}, cache map[int64]*gl.Project, since, until time.Time) ([]report.Event, error) {
@@ -106,7 +248,7 @@ func fetchCIFailures(ctx context.Context, client *gl.Client, username string, pr
 			Status:        &status,
 			Username:      new(username),
 			UpdatedAfter:  new(since),
-			UpdatedBefore: new(until.AddDate(0, 0, 1)),
+			UpdatedBefore: new(until),
 			ListOptions:   gl.ListOptions{PerPage: 100},
 		}
 		pipelines, _, err := client.Pipelines.ListProjectPipelines(pid, opts, gl.WithContext(ctx))
@@ -132,14 +274,8 @@ func fetchCIFailures(ctx context.Context, client *gl.Client, username string, pr
 	return events, nil
 }
 
-func fetchPendingReviews(ctx context.Context, client *gl.Client, userID int64, cacheSnapshot map[int64]*gl.Project) ([]report.Event, error) {
-	opts := &gl.ListMergeRequestsOptions{
-		State:       new("opened"),
-		ReviewerID:  gl.ReviewerID(userID),
-		Scope:       new("all"),
-		ListOptions: gl.ListOptions{PerPage: 100},
-	}
-	mrs, _, err := client.MergeRequests.ListMergeRequests(opts, gl.WithContext(ctx))
+func fetchPendingReviews(ctx context.Context, client *gl.Client, userID int64, filter report.RepoFilter, cacheSnapshot map[int64]*gl.Project) ([]report.Event, error) {
+	mrs, err := listReviewRequests(ctx, client, userID, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -171,10 +307,42 @@ func fetchPendingReviews(ctx context.Context, client *gl.Client, userID int64, c
 	return events, nil
 }
 
-func newClient(token string) (*gl.Client, error) {
+// listReviewRequests lists the open merge requests awaiting the user's
+// review. When the filter only includes literal groups, the listing is scoped
+// to those groups instead of the whole instance.
+func listReviewRequests(ctx context.Context, client *gl.Client, userID int64, filter report.RepoFilter) ([]*gl.BasicMergeRequest, error) {
+	if groups, repos, ok := filter.LiteralIncludes(); ok && len(repos) == 0 {
+		var mrs []*gl.BasicMergeRequest
+		for _, group := range groups {
+			opts := &gl.ListGroupMergeRequestsOptions{
+				State:       new("opened"),
+				ReviewerID:  gl.ReviewerID(userID),
+				Scope:       new("all"),
+				ListOptions: gl.ListOptions{PerPage: 100},
+			}
+			groupMRs, _, err := client.MergeRequests.ListGroupMergeRequests(group, opts, gl.WithContext(ctx))
+			if err != nil {
+				return nil, err
+			}
+			mrs = append(mrs, groupMRs...)
+		}
+		return mrs, nil
+	}
+
+	opts := &gl.ListMergeRequestsOptions{
+		State:       new("opened"),
+		ReviewerID:  gl.ReviewerID(userID),
+		Scope:       new("all"),
+		ListOptions: gl.ListOptions{PerPage: 100},
+	}
+	mrs, _, err := client.MergeRequests.ListMergeRequests(opts, gl.WithContext(ctx))
+	return mrs, err
+}
+
+func newClient(token, baseURL string) (*gl.Client, error) {
 	opts := []gl.ClientOptionFunc{}
-	if u := os.Getenv("GITLAB_URL"); u != "" {
-		opts = append(opts, gl.WithBaseURL(strings.TrimRight(u, "/")))
+	if baseURL != "" {
+		opts = append(opts, gl.WithBaseURL(strings.TrimRight(baseURL, "/")))
 	}
 	return gl.NewClient(token, opts...)
 }
@@ -207,12 +375,24 @@ func parseEvent(e *gl.ContributionEvent, proj *gl.Project) []report.Event {
 		if title == "" {
 			title = fmt.Sprintf("%d commit(s) to %s", e.PushData.CommitCount, e.PushData.Ref)
 		}
+		// Push events only identify the head commit of the push.
+		var url string
+		if e.PushData.CommitTo != "" {
+			url = fmt.Sprintf("%s/-/commit/%s", proj.WebURL, e.PushData.CommitTo)
+		}
+		var branch string
+		if e.PushData.RefType == "branch" {
+			branch = e.PushData.Ref
+		}
 		return []report.Event{{
 			Category:  report.CategoryCommit,
 			Action:    "pushed",
 			Title:     title,
+			URL:       url,
 			Repo:      repoName,
 			Source:    "gitlab",
+			SHA:       e.PushData.CommitTo,
+			Branch:    branch,
 			CreatedAt: createdAt,
 		}}
 
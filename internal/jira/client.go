@@ -0,0 +1,71 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// client is a minimal Jira REST API v2 client. Version 2 is served by both
+// Jira Cloud and Jira Server / Data Center.
+type client struct {
+	http    *http.Client
+	baseURL string
+	email   string
+	token   string
+}
+
+func newClient(baseURL, email, token string) *client {
+	return &client{
+		http:    http.DefaultClient,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		email:   email,
+		token:   token,
+	}
+}
+
+// isCloud reports whether the client talks to an Atlassian-hosted site.
+func (c *client) isCloud() bool {
+	u, err := url.Parse(c.baseURL)
+	return err == nil && strings.HasSuffix(u.Hostname(), ".atlassian.net")
+}
+
+// get issues a GET request for path with the given query parameters and
+// decodes the JSON response into v. Cloud API tokens use basic auth with the
+// account email; Server personal access tokens are sent as bearer tokens.
+func (c *client) get(ctx context.Context, path string, query map[string]string, v any) error {
+	u, err := url.Parse(c.baseURL + path)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	for k, val := range query {
+		q.Set(k, val)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.email != "" {
+		req.SetBasicAuth(c.email, c.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
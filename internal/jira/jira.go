@@ -0,0 +1,338 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"worklog/internal/provider"
+	"worklog/internal/report"
+)
+
+func init() {
+	provider.Register("jira", func() provider.Provider { return &Provider{} })
+}
+
+// Provider reports Jira issues the user created, transitioned, commented on
+// or logged work against, on the Jira Cloud or Server instance at JIRA_URL.
+type Provider struct {
+	email    string
+	token    string
+	baseURL  string
+	warnings []string
+}
+
+func (p *Provider) Name() string { return "jira" }
+
+// Configure reads JIRA_URL and JIRA_TOKEN, plus JIRA_EMAIL for Jira Cloud
+// API tokens. Server personal access tokens need no email.
+func (p *Provider) Configure(lookup func(string) string) (bool, error) {
+	p.email = lookup("EMAIL")
+	p.token = lookup("TOKEN")
+	p.baseURL = lookup("URL")
+	if p.token == "" {
+		return false, nil
+	}
+	if p.baseURL == "" {
+		return false, fmt.Errorf("JIRA_URL must be set when JIRA_TOKEN is set")
+	}
+	return true, nil
+}
+
+func (p *Provider) Identity() string {
+	return p.baseURL + "\x00" + p.email + "\x00" + p.token
+}
+
+func (p *Provider) FetchEvents(ctx context.Context, since, until time.Time) ([]report.Event, error) {
+	events, warnings, err := FetchEvents(ctx, p.email, p.token, p.baseURL, since, until)
+	p.warnings = warnings
+	return events, err
+}
+
+// Warnings returns the gaps in coverage found by the last FetchEvents, such
+// as issues beyond the search limit or lists that could not be read in full.
+func (p *Provider) Warnings() []string { return p.warnings }
+
+// jiraTime parses Jira's timestamp format, which has no colon in the zone
+// offset and therefore is not RFC 3339.
+type jiraTime struct {
+	time.Time
+}
+
+func (t *jiraTime) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil || s == "" {
+		return nil
+	}
+	parsed, err := time.Parse("2006-01-02T15:04:05.000-0700", s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+type user struct {
+	AccountID string `json:"accountId"` // Cloud
+	Name      string `json:"name"`      // Server
+}
+
+// is reports whether u and other identify the same account. Cloud exposes
+// only account IDs, while Server identifies users by name.
+func (u user) is(other user) bool {
+	if u.AccountID != "" {
+		return u.AccountID == other.AccountID
+	}
+	return u.Name != "" && u.Name == other.Name
+}
+
+type comment struct {
+	ID      string   `json:"id"`
+	Author  user     `json:"author"`
+	Created jiraTime `json:"created"`
+}
+
+type worklog struct {
+	Author    user     `json:"author"`
+	Started   jiraTime `json:"started"`
+	TimeSpent int      `json:"timeSpentSeconds"`
+}
+
+type history struct {
+	Author  user     `json:"author"`
+	Created jiraTime `json:"created"`
+	Items   []struct {
+		Field    string `json:"field"`
+		ToString string `json:"toString"`
+	} `json:"items"`
+}
+
+type issue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary string   `json:"summary"`
+		Created jiraTime `json:"created"`
+		Creator user     `json:"creator"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+		Comment struct {
+			Comments []comment `json:"comments"`
+			Total    int       `json:"total"`
+		} `json:"comment"`
+		Worklog struct {
+			Worklogs []worklog `json:"worklogs"`
+			Total    int       `json:"total"`
+		} `json:"worklog"`
+	} `json:"fields"`
+	Changelog changelog `json:"changelog"`
+}
+
+type changelog struct {
+	Histories []history `json:"histories"`
+	Total     int       `json:"total"`
+}
+
+// listPage is a page of the worklogs, comments or changelog of an issue,
+// which are listed under different names.
+type listPage[T any] struct {
+	Total    int `json:"total"`
+	Worklogs []T `json:"worklogs"`
+	Comments []T `json:"comments"`
+	Values   []T `json:"values"`
+}
+
+type searchResult struct {
+	Issues []issue `json:"issues"`
+
+	// Cloud (/search/jql) paginates with a token, Server (/search) with offsets.
+	NextPageToken string `json:"nextPageToken"`
+	StartAt       int    `json:"startAt"`
+	Total         int    `json:"total"`
+}
+
+// FetchEvents returns the user's activity between since and until, and
+// warnings about activity it could not read.
+func FetchEvents(ctx context.Context, email, token, baseURL string, since, until time.Time) ([]report.Event, []string, error) {
+	c := newClient(baseURL, email, token)
+
+	var me user
+	if err := c.get(ctx, "/rest/api/2/myself", nil, &me); err != nil {
+		return nil, nil, fmt.Errorf("getting user: %w", err)
+	}
+
+	issues, warnings, err := searchIssues(ctx, c, since, until)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var events []report.Event
+	for _, is := range issues {
+		if err := completeIssue(ctx, c, &is); err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not read all activity on %s: %v", is.Key, err))
+		}
+		events = append(events, issueEvents(c.baseURL, me, is, since, until)...)
+	}
+	return events, warnings, nil
+}
+
+// searchIssues finds the issues the user may have touched within the range.
+// JQL cannot express "commented by", so the query casts a wide net and
+// issueEvents keeps only the user's own actions. JQL dates are read in the
+// timezone of the user's Jira profile, which may differ from the report's, so
+// the range is widened by a day on each side. At most 20 pages are read, and
+// a warning says so when more remain.
+func searchIssues(ctx context.Context, c *client, since, until time.Time) ([]issue, []string, error) {
+	from := since.AddDate(0, 0, -1).Format("2006-01-02 15:04")
+	to := until.AddDate(0, 0, 1).Format("2006-01-02 15:04")
+	jql := fmt.Sprintf(`updated >= "%s" AND (creator = currentUser() OR assignee was currentUser() `+
+		`OR reporter = currentUser() OR watcher = currentUser() OR worklogAuthor = currentUser() `+
+		`OR status CHANGED BY currentUser() DURING ("%s", "%s")) ORDER BY updated DESC`, from, from, to)
+
+	query := map[string]string{
+		"jql":        jql,
+		"fields":     "summary,created,creator,project,comment,worklog",
+		"expand":     "changelog",
+		"maxResults": "50",
+	}
+
+	const maxPages = 20
+	var issues []issue
+	for page := range maxPages {
+		var result searchResult
+		path := "/rest/api/2/search"
+		if c.isCloud() {
+			path = "/rest/api/2/search/jql"
+		}
+		if err := c.get(ctx, path, query, &result); err != nil {
+			return nil, nil, fmt.Errorf("searching issues: %w", err)
+		}
+		issues = append(issues, result.Issues...)
+
+		if c.isCloud() {
+			if result.NextPageToken == "" {
+				return issues, nil, nil
+			}
+			query["nextPageToken"] = result.NextPageToken
+		} else {
+			next := result.StartAt + len(result.Issues)
+			if len(result.Issues) == 0 || next >= result.Total {
+				return issues, nil, nil
+			}
+			query["startAt"] = strconv.Itoa(next)
+		}
+		if page == maxPages-1 {
+			warning := fmt.Sprintf("only the %d most recently updated issues were read; activity on the others is missing", len(issues))
+			return issues, []string{warning}, nil
+		}
+	}
+	return issues, nil, nil
+}
+
+// completeIssue reads the worklogs, comments and changelog of an issue from
+// their own endpoints when the search returned only the first of them.
+func completeIssue(ctx context.Context, c *client, is *issue) error {
+	path := "/rest/api/2/issue/" + is.Key
+	if f := &is.Fields.Worklog; len(f.Worklogs) < f.Total {
+		all, err := getAll[worklog](ctx, c, path+"/worklog")
+		if err != nil {
+			return err
+		}
+		f.Worklogs = all
+	}
+	if f := &is.Fields.Comment; len(f.Comments) < f.Total {
+		all, err := getAll[comment](ctx, c, path+"/comment")
+		if err != nil {
+			return err
+		}
+		f.Comments = all
+	}
+	if cl := &is.Changelog; len(cl.Histories) < cl.Total {
+		if c.isCloud() {
+			all, err := getAll[history](ctx, c, path+"/changelog")
+			if err != nil {
+				return err
+			}
+			cl.Histories = all
+		} else {
+			// Server has no changelog endpoint, but returns the whole
+			// changelog of a single issue.
+			var full issue
+			if err := c.get(ctx, path, map[string]string{"fields": "none", "expand": "changelog"}, &full); err != nil {
+				return err
+			}
+			cl.Histories = full.Changelog.Histories
+		}
+	}
+	return nil
+}
+
+// getAll reads every page of a list under an issue.
+func getAll[T any](ctx context.Context, c *client, path string) ([]T, error) {
+	var all []T
+	for {
+		var page listPage[T]
+		query := map[string]string{"startAt": strconv.Itoa(len(all)), "maxResults": "100"}
+		if err := c.get(ctx, path, query, &page); err != nil {
+			return nil, err
+		}
+		items := slices.Concat(page.Worklogs, page.Comments, page.Values)
+		all = append(all, items...)
+		if len(items) == 0 || len(all) >= page.Total {
+			return all, nil
+		}
+	}
+}
+
+func issueEvents(baseURL string, me user, is issue, since, until time.Time) []report.Event {
+	url := fmt.Sprintf("%s/browse/%s", baseURL, is.Key)
+	title := fmt.Sprintf("%s %s", is.Key, is.Fields.Summary)
+
+	var events []report.Event
+	add := func(cat report.EventCategory, action, url string, at time.Time) *report.Event {
+		if at.Before(since) || at.After(until) {
+			return nil
+		}
+		events = append(events, report.Event{
+			Category:  cat,
+			Action:    action,
+			Title:     title,
+			URL:       url,
+			Repo:      is.Fields.Project.Key,
+			Source:    "jira",
+			Ticket:    is.Key,
+			CreatedAt: at,
+		})
+		return &events[len(events)-1]
+	}
+
+	if me.is(is.Fields.Creator) {
+		add(report.CategoryIssue, "opened", url, is.Fields.Created.Time)
+	}
+	for _, h := range is.Changelog.Histories {
+		if !me.is(h.Author) {
+			continue
+		}
+		for _, item := range h.Items {
+			if item.Field == "status" {
+				add(report.CategoryTransition, "moved to "+item.ToString, url, h.Created.Time)
+			}
+		}
+	}
+	for _, cm := range is.Fields.Comment.Comments {
+		if me.is(cm.Author) {
+			add(report.CategoryComment, "commented", fmt.Sprintf("%s?focusedCommentId=%s", url, cm.ID), cm.Created.Time)
+		}
+	}
+	for _, wl := range is.Fields.Worklog.Worklogs {
+		if !me.is(wl.Author) || wl.TimeSpent <= 0 {
+			continue
+		}
+		if e := add(report.CategoryIssue, "logged", url, wl.Started.Time); e != nil {
+			e.TimeSpent = time.Duration(wl.TimeSpent) * time.Second
+		}
+	}
+	return events
+}
@@ -0,0 +1,44 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"worklog/internal/testutil"
+)
+
+// TestFetchEventsReadsTruncatedLists checks that the worklogs, comments and
+// changelog of an issue are read from their own endpoints when the search
+// embeds only the first of them.
+func TestFetchEventsReadsTruncatedLists(t *testing.T) {
+	var fixtures map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	fixtures = testutil.Fixtures(t, "testdata/server.json", srv.URL)
+
+	events, warnings, err := FetchEvents(context.Background(), "", "token", srv.URL, testutil.Since, testutil.Until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("got warnings %q, want none", warnings)
+	}
+	url := srv.URL + "/browse/ABC-1"
+	testutil.CheckEvents(t, events, []string{
+		`Issue Transitions | moved to Done | ABC-1 Slow search | ABC | ` + url + ` | jira | 2026-10-15T09:00:00Z`,
+		`Comments | commented | ABC-1 Slow search | ABC | ` + url + `?focusedCommentId=2 | jira | 2026-10-14T09:00:00Z`,
+		`Issues | logged | ABC-1 Slow search | ABC | ` + url + ` | jira | 2026-10-13T10:00:00Z`,
+		`Issues | logged | ABC-1 Slow search | ABC | ` + url + ` | jira | 2026-10-14T11:00:00Z`,
+	})
+}
@@ -0,0 +1,221 @@
+package localgit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"worklog/internal/provider"
+	"worklog/internal/report"
+)
+
+func init() {
+	provider.Register("localgit", func() provider.Provider { return &Provider{} })
+}
+
+// Provider reports commits found in local git repositories under the
+// directories listed in LOCALGIT_DIRS, including commits on branches that
+// have not been pushed yet. It needs no network access.
+type Provider struct {
+	dirs   []string
+	emails []string
+	filter report.RepoFilter
+}
+
+func (p *Provider) Name() string { return "localgit" }
+
+// Configure reads LOCALGIT_DIRS as a path list (separated like PATH) and
+// LOCALGIT_EMAILS as a comma-separated list of author emails, which defaults
+// to the global git user.email.
+func (p *Provider) Configure(lookup func(string) string) (bool, error) {
+	p.dirs = nil
+	for _, dir := range filepath.SplitList(lookup("DIRS")) {
+		if dir == "" {
+			continue
+		}
+		p.dirs = append(p.dirs, expandHome(dir))
+	}
+	if len(p.dirs) == 0 {
+		return false, nil
+	}
+
+	p.emails = splitList(lookup("EMAILS"))
+	if len(p.emails) == 0 {
+		out, err := exec.Command("git", "config", "--global", "user.email").Output()
+		if err != nil {
+			return false, fmt.Errorf("LOCALGIT_EMAILS is not set and git user.email could not be read: %w", err)
+		}
+		p.emails = splitList(string(out))
+	}
+	return true, nil
+}
+
+func (p *Provider) Identity() string {
+	return strings.Join(p.dirs, "\x00") + "\x00\x00" + strings.Join(p.emails, "\x00")
+}
+
+func (p *Provider) SetRepoFilter(f report.RepoFilter) { p.filter = f }
+
+func (p *Provider) FetchEvents(ctx context.Context, since, until time.Time) ([]report.Event, error) {
+	return FetchEvents(ctx, p.dirs, p.emails, p.filter, since, until)
+}
+
+func FetchEvents(ctx context.Context, dirs, emails []string, filter report.RepoFilter, since, until time.Time) ([]report.Event, error) {
+	authors := make(map[string]struct{}, len(emails))
+	for _, e := range emails {
+		authors[strings.ToLower(e)] = struct{}{}
+	}
+
+	var events []report.Event
+	for _, repo := range findRepos(dirs) {
+		name := repoName(ctx, repo)
+		if !filter.Match(name) {
+			continue
+		}
+		repoEvents, err := repoCommits(ctx, repo, name, authors, since, until)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: localgit %s: %v\n", repo, err)
+			continue
+		}
+		events = append(events, repoEvents...)
+	}
+	return events, nil
+}
+
+// findRepos returns the git working trees at or below each of dirs. It does
+// not descend into a repository once found, so nested repositories such as
+// submodules are not scanned separately.
+func findRepos(dirs []string) []string {
+	var repos []string
+	for _, dir := range dirs {
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if path != dir && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			// .git is a directory in regular clones and a file in worktrees.
+			if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+				repos = append(repos, path)
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	return repos
+}
+
+// repoCommits lists commits on any local ref authored by one of authors
+// between since and until. Commits not reachable from a remote-tracking
+// branch are reported as "committed" rather than "pushed".
+func repoCommits(ctx context.Context, repo, repoName string, authors map[string]struct{}, since, until time.Time) ([]report.Event, error) {
+	// Filtering on committer date is a cheap lower bound: a commit cannot
+	// be committed before it was authored.
+	out, err := git(ctx, repo, "log", "--all", "--since="+since.Format(time.RFC3339),
+		"--format=%H%x1f%ae%x1f%aI%x1f%s%x1e")
+	if err != nil {
+		return nil, err
+	}
+
+	unpushedOut, err := git(ctx, repo, "rev-list", "--all", "--not", "--remotes")
+	if err != nil {
+		return nil, err
+	}
+	unpushed := make(map[string]struct{})
+	for _, sha := range strings.Fields(unpushedOut) {
+		unpushed[sha] = struct{}{}
+	}
+
+	var events []report.Event
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		sha, email, date, subject := fields[0], fields[1], fields[2], fields[3]
+		if _, ok := authors[strings.ToLower(email)]; !ok {
+			continue
+		}
+		authoredAt, err := time.Parse(time.RFC3339, date)
+		if err != nil || authoredAt.Before(since) || authoredAt.After(until) {
+			continue
+		}
+		action := "pushed"
+		if _, ok := unpushed[sha]; ok {
+			action = "committed"
+		}
+		events = append(events, report.Event{
+			Category:  report.CategoryCommit,
+			Action:    action,
+			Title:     subject,
+			Repo:      repoName,
+			Source:    "localgit",
+			SHA:       sha,
+			CreatedAt: authoredAt,
+		})
+	}
+	return events, nil
+}
+
+// repoName derives an "owner/repo" name from the origin remote so that local
+// commits line up with those reported by hosting providers, falling back to
+// the directory name for repositories without an origin.
+func repoName(ctx context.Context, repo string) string {
+	remote, err := git(ctx, repo, "remote", "get-url", "origin")
+	if err != nil {
+		return filepath.Base(repo)
+	}
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	if i := strings.Index(remote, "://"); i >= 0 {
+		// https://host/owner/repo or ssh://git@host:port/owner/repo
+		remote = remote[i+3:]
+		if j := strings.Index(remote, "/"); j >= 0 {
+			return remote[j+1:]
+		}
+	}
+	// scp-like syntax: git@host:owner/repo
+	if _, path, ok := strings.Cut(remote, ":"); ok {
+		return path
+	}
+	return filepath.Base(repo)
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"worklog/internal/report"
+)
+
+// Provider is a source of activity events, such as GitHub or GitLab.
+type Provider interface {
+	// Name returns the identifier used for flags and warnings, e.g. "github".
+	Name() string
+
+	// Configure loads the provider's settings through lookup, which resolves
+	// unprefixed keys such as "TOKEN" or "URL". It reports whether enough
+	// configuration was found for the provider to run.
+	Configure(lookup func(key string) string) (bool, error)
+
+	// Identity returns what the configured provider reads activity as, such
+	// as its server URL and credentials, so that the event cache keeps the
+	// activity of different servers and users apart. It is only stored
+	// hashed.
+	Identity() string
+
+	// FetchEvents returns the user's activity between since and until, inclusive.
+	FetchEvents(ctx context.Context, since, until time.Time) ([]report.Event, error)
+}
+
+// RepoFilterer is implemented by providers that can narrow their queries to
+// the repositories a report will keep. The filter is applied to all events
+// afterwards as well, so pushing it down only saves requests.
+type RepoFilterer interface {
+	SetRepoFilter(f report.RepoFilter)
+}
+
+var registry = make(map[string]func() Provider)
+
+// Register makes a provider available under name. It is intended to be called
+// from the init function of the package implementing the provider, and panics
+// if name is registered twice.
+func Register(name string, factory func() Provider) {
+	if _, dup := registry[name]; dup {
+		panic("provider: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// Names returns the names of all registered providers in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns a fresh, unconfigured instance of the named provider.
+func New(name string) (Provider, bool) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// Warner is implemented by providers that can explain gaps in the events
+// returned by FetchEvents, such as a range their API could not fully cover.
+// The warnings are shown in the report itself rather than only on stderr.
+type Warner interface {
+	Warnings() []string
+}
+
+// Account is a configured provider instance. Label distinguishes several
+// accounts of the same provider and is empty for the default account.
+type Account struct {
+	Label    string
+	Provider Provider
+}
+
+// String returns the account as "name" or "name:label", e.g. "github:work".
+func (a Account) String() string {
+	if a.Label == "" {
+		return a.Provider.Name()
+	}
+	return a.Provider.Name() + ":" + a.Label
+}
+
+// CacheKey identifies the account's events in the local cache: its name
+// and a hash of the provider's Identity, so that pointing an account at
+// another server or user does not reuse the events cached for the previous
+// one.
+func (a Account) CacheKey() string {
+	sum := sha256.Sum256([]byte(a.Provider.Identity()))
+	return a.String() + "@" + hex.EncodeToString(sum[:8])
+}
+
+// EnvAccounts returns the labels of the named accounts listed for the provider
+// in the comma-separated <NAME>_ACCOUNTS variable, e.g. GITHUB_ACCOUNTS=work,oss.
+func EnvAccounts(name string, getenv func(string) string) []string {
+	var labels []string
+	for _, label := range strings.Split(getenv(strings.ToUpper(name)+"_ACCOUNTS"), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// EnvLookup returns a lookup function that resolves keys against environment
+// variables prefixed with the upper-cased provider name, so that "TOKEN" for
+// the "github" provider reads GITHUB_TOKEN. For a named account the label is
+// part of the prefix, so the "work" account reads GITHUB_WORK_TOKEN.
+func EnvLookup(name, label string, getenv func(string) string) func(string) string {
+	prefix := envName(name) + "_"
+	if label != "" {
+		prefix += envName(label) + "_"
+	}
+	return func(key string) string {
+		return getenv(prefix + key)
+	}
+}
+
+// envName upper-cases s and replaces characters that are not valid in
+// environment variable names with underscores.
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
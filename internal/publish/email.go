@@ -0,0 +1,295 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"worklog/internal/report"
+)
+
+func init() {
+	Register("email", func() Publisher { return &Email{} })
+}
+
+const defaultSubject = `{{with .Sprint}}{{.}}{{else}}Standup Report{{end}} ({{date "Jan 2" .Since}} – {{date "Jan 2" .Until}})`
+
+// Email sends the report over SMTP as a multipart message with a plain-text
+// part and an HTML part with linked titles.
+type Email struct {
+	host     string
+	port     string
+	security string // "starttls", "tls" or "none"
+	username string
+	password string
+	from     *mail.Address
+	to       []*mail.Address
+	cc       []*mail.Address
+	subject  *texttemplate.Template
+}
+
+// Configure reads HOST, PORT (default 587, or 465 with TLS "tls"), TLS
+// ("starttls", "tls" or "none"; default "starttls"), USERNAME and PASSWORD
+// for authentication, FROM, TO and CC as comma-separated address lists, and
+// SUBJECT, a template over the report's Since and Until times.
+func (e *Email) Configure(lookup func(string) string) error {
+	e.host = lookup("HOST")
+	if e.host == "" {
+		return fmt.Errorf("host or EMAIL_HOST must be set")
+	}
+
+	e.security = strings.ToLower(lookup("TLS"))
+	switch e.security {
+	case "":
+		e.security = "starttls"
+	case "starttls", "tls", "none":
+	default:
+		return fmt.Errorf("invalid tls %q: must be starttls, tls or none", e.security)
+	}
+	e.port = lookup("PORT")
+	if e.port == "" {
+		e.port = "587"
+		if e.security == "tls" {
+			e.port = "465"
+		}
+	}
+
+	e.username = lookup("USERNAME")
+	e.password = lookup("PASSWORD")
+
+	var err error
+	from := lookup("FROM")
+	if from == "" {
+		from = e.username
+	}
+	if e.from, err = mail.ParseAddress(from); err != nil {
+		return fmt.Errorf("invalid from %q: %w", from, err)
+	}
+	if e.to, err = parseAddressList(lookup("TO")); err != nil {
+		return fmt.Errorf("invalid to: %w", err)
+	}
+	if len(e.to) == 0 {
+		return fmt.Errorf("to or EMAIL_TO must be set")
+	}
+	if e.cc, err = parseAddressList(lookup("CC")); err != nil {
+		return fmt.Errorf("invalid cc: %w", err)
+	}
+
+	subject := lookup("SUBJECT")
+	if subject == "" {
+		subject = defaultSubject
+	}
+	if e.subject, err = texttemplate.New("subject").Funcs(report.TemplateFuncs).Parse(subject); err != nil {
+		return fmt.Errorf("invalid subject: %w", err)
+	}
+	return nil
+}
+
+func parseAddressList(s string) ([]*mail.Address, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	return mail.ParseAddressList(s)
+}
+
+// Payload returns the message in RFC 5322 format, as it is sent.
+func (e *Email) Payload(events []report.Event, since, until time.Time, opts report.Options) ([]byte, error) {
+	var subject strings.Builder
+	data := struct {
+		Since, Until time.Time
+		Sprint       string
+	}{since, until, opts.Sprint}
+	if err := e.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+
+	text, err := report.Generate(events, since, until, "text", opts)
+	if err != nil {
+		return nil, err
+	}
+	var html bytes.Buffer
+	if err := emailHTML.Execute(&html, emailData{
+		Title:      report.Title(since, until, opts),
+		Notice:     report.Notice(opts),
+		Categories: report.Categories(events),
+		Opts:       opts,
+	}); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	mw := multipart.NewWriter(&msg)
+	header := []string{
+		"From: " + e.from.String(),
+		"To: " + addressList(e.to),
+	}
+	if len(e.cc) > 0 {
+		header = append(header, "Cc: "+addressList(e.cc))
+	}
+	header = append(header,
+		"Subject: "+mime.QEncoding.Encode("utf-8", subject.String()),
+		"Date: "+time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary="+mw.Boundary(),
+	)
+	msg.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html.String()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(w)
+		if _, err := qw.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+func addressList(addrs []*mail.Address) string {
+	s := make([]string, len(addrs))
+	for i, a := range addrs {
+		s[i] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+func (e *Email) Publish(ctx context.Context, events []report.Event, since, until time.Time, opts report.Options) error {
+	msg, err := e.Payload(events, since, until, opts)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(e.host, e.port)
+	tlsConfig := &tls.Config{ServerName: e.host}
+	var conn net.Conn
+	if e.security == "tls" {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.security == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS; set tls to \"tls\" or \"none\"", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if e.username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted
+		// connection to anything but localhost.
+		if err := c.Auth(smtp.PlainAuth("", e.username, e.password, e.host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(e.from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range append(e.to, e.cc...) {
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+type emailData struct {
+	Title      string
+	Notice     []string
+	Categories []report.CategoryGroup
+	Opts       report.Options
+}
+
+var emailHTML = template.Must(template.New("email").Funcs(template.FuncMap{
+	"action": report.ActionText,
+	"header": categoryHeader,
+	"source": report.SourceLabel,
+	"short":  report.ShortSHA,
+}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>{{.Title}}</h2>
+{{- range .Notice}}
+<p><em>{{.}}</em></p>
+{{- end}}
+{{- range .Categories}}
+<h3>{{header . $.Opts}}</h3>
+<ul>
+{{- range .Events}}
+<li>{{action .}} {{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}
+<code>{{.Repo}}</code> · {{source .}}
+{{- range .Tickets}} → {{if .URL}}<a href="{{.URL}}">{{.Key}}</a>{{else}}{{.Key}}{{end}}{{end}}
+{{- with .Commits}}
+<ul>
+{{- range .}}
+<li><code>{{short .SHA}}</code> {{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>No activity found for this period.</p>
+{{- end}}
+{{- range .Opts.Notes}}
+<h3>{{.Heading}}</h3>
+<ul>
+{{- range .Items}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
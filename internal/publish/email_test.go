@@ -0,0 +1,303 @@
+package publish
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"worklog/internal/report"
+	"worklog/internal/testutil"
+)
+
+// smtpServer is an SMTP server for one session that records what it is
+// sent. It offers STARTTLS when cert is set, and accepts the credentials
+// only when authOK.
+type smtpServer struct {
+	ln     net.Listener
+	cert   *tls.Certificate
+	authOK bool
+	done   chan struct{}
+
+	auth string // decoded AUTH PLAIN response
+	from string
+	rcpt []string
+	data []byte
+}
+
+func newSMTPServer(t *testing.T, cert *tls.Certificate, authOK bool) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{ln: ln, cert: cert, authOK: authOK, done: make(chan struct{})}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) port() string {
+	_, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return port
+}
+
+// wait returns once the client has hung up.
+func (s *smtpServer) wait() {
+	<-s.done
+}
+
+func (s *smtpServer) serve() {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { conn.Close() }()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tp.PrintfLine("250-localhost")
+			if s.cert != nil {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*s.cert}})
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			_, resp, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(resp)
+			s.auth = string(decoded)
+			if !s.authOK {
+				tp.PrintfLine("535 5.7.8 authentication failed")
+				continue
+			}
+			tp.PrintfLine("235 accepted")
+		case "MAIL":
+			s.from = arg
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			s.rcpt = append(s.rcpt, arg)
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			if s.data, err = tp.ReadDotBytes(); err != nil {
+				return
+			}
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func newEmail(t *testing.T, s *smtpServer, settings map[string]string) *Email {
+	t.Helper()
+	config := map[string]string{
+		"HOST": "127.0.0.1",
+		"PORT": s.port(),
+		"FROM": "Me <me@example.com>",
+		"TO":   "team@example.com",
+	}
+	for k, v := range settings {
+		config[k] = v
+	}
+	e := &Email{}
+	if err := e.Configure(lookup(config)); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// untrustedCert returns a certificate for 127.0.0.1 that is not signed by a
+// trusted authority.
+func untrustedCert(t *testing.T) *tls.Certificate {
+	t.Helper()
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	return &srv.TLS.Certificates[0]
+}
+
+func TestEmailPublish(t *testing.T) {
+	s := newSMTPServer(t, nil, true)
+	e := newEmail(t, s, map[string]string{
+		"TLS":      "none",
+		"USERNAME": "me@example.com",
+		"PASSWORD": "secret",
+		"TO":       "team@example.com, Lead <lead@example.com>",
+		"CC":       "boss@example.com",
+	})
+	opts := report.Options{Notes: []report.Note{{Heading: "Blockers", Items: []string{"waiting on review"}}}}
+	if err := e.Publish(context.Background(), pullRequests(2), testutil.Since, testutil.Until, opts); err != nil {
+		t.Fatal(err)
+	}
+	s.wait()
+
+	if s.auth != "\x00me@example.com\x00secret" {
+		t.Errorf("got credentials %q", s.auth)
+	}
+	if s.from != "FROM:<me@example.com>" {
+		t.Errorf("got sender %q", s.from)
+	}
+	want := []string{"TO:<team@example.com>", "TO:<lead@example.com>", "TO:<boss@example.com>"}
+	if strings.Join(s.rcpt, " ") != strings.Join(want, " ") {
+		t.Errorf("got recipients %q, want %q", s.rcpt, want)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(s.data)))
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := map[string]string{
+		"From":         msg.Header.Get("From"),
+		"To":           msg.Header.Get("To"),
+		"Cc":           msg.Header.Get("Cc"),
+		"Subject":      subject,
+		"MIME-Version": msg.Header.Get("MIME-Version"),
+	}
+	for name, want := range map[string]string{
+		"From":         `"Me" <me@example.com>`,
+		"To":           `<team@example.com>, "Lead" <lead@example.com>`,
+		"Cc":           "<boss@example.com>",
+		"Subject":      "Standup Report (Oct 12 – Oct 16)",
+		"MIME-Version": "1.0",
+	} {
+		if headers[name] != want {
+			t.Errorf("got %s %q, want %q", name, headers[name], want)
+		}
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("invalid Date: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got Content-Type %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+	parts := make(map[string]string)
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The reader decodes quoted-printable parts.
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[p.Header.Get("Content-Type")] = string(body)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("got parts %q, want text and HTML", parts)
+	}
+	text := parts["text/plain; charset=utf-8"]
+	for _, want := range []string{"Standup Report (Oct 12 – Oct 16)", "Opened #1 Change 1", "Blockers:", "waiting on review"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part lacks %q:\n%s", want, text)
+		}
+	}
+	html := parts["text/html; charset=utf-8"]
+	for _, want := range []string{`<a href="https://github.com/acme/api/pull/1">#1 Change 1</a>`, "<h3>Blockers</h3>", "<li>waiting on review</li>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML part lacks %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestEmailSTARTTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		cert *tls.Certificate
+		err  string
+	}{
+		{"not offered", nil, "does not support STARTTLS"},
+		{"untrusted certificate", untrustedCert(t), "starttls: tls: failed to verify certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSMTPServer(t, tt.cert, true)
+			e := newEmail(t, s, map[string]string{"USERNAME": "me@example.com", "PASSWORD": "secret"})
+			err := e.Publish(context.Background(), pullRequests(1), testutil.Since, testutil.Until, report.Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+			s.wait()
+			if s.auth != "" || s.data != nil {
+				t.Error("credentials or message sent without TLS")
+			}
+		})
+	}
+}
+
+func TestEmailAuthFailure(t *testing.T) {
+	s := newSMTPServer(t, nil, false)
+	e := newEmail(t, s, map[string]string{"TLS": "none", "USERNAME": "me@example.com", "PASSWORD": "wrong"})
+	err := e.Publish(context.Background(), pullRequests(1), testutil.Since, testutil.Until, report.Options{})
+	if err == nil || !strings.HasPrefix(err.Error(), "auth: 535") {
+		t.Fatalf("got error %v, want the server's rejection", err)
+	}
+	s.wait()
+	if s.from != "" || s.data != nil {
+		t.Error("message sent after authentication failed")
+	}
+}
+
+func TestEmailConfigure(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		err      string
+	}{
+		{map[string]string{"TO": "a@example.com", "FROM": "me@example.com"}, "host or EMAIL_HOST must be set"},
+		{map[string]string{"HOST": "mail", "FROM": "me@example.com"}, "to or EMAIL_TO must be set"},
+		{map[string]string{"HOST": "mail", "TO": "a@example.com", "FROM": "me@example.com", "TLS": "ssl"}, `invalid tls "ssl"`},
+		{map[string]string{"HOST": "mail", "TO": "a@example.com"}, `invalid from ""`},
+		{map[string]string{"HOST": "mail", "TO": "a@example.com", "FROM": "me@example.com", "SUBJECT": "{{.Nope"}, "invalid subject"},
+	}
+	for _, tt := range tests {
+		err := (&Email{}).Configure(lookup(tt.settings))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Configure(%v) = %v, want %q", tt.settings, err, tt.err)
+		}
+	}
+
+	e := &Email{}
+	if err := e.Configure(lookup(map[string]string{"HOST": "mail", "TO": "a@example.com", "USERNAME": "me@example.com", "TLS": "TLS"})); err != nil {
+		t.Fatal(err)
+	}
+	if e.port != "465" || e.from.Address != "me@example.com" {
+		t.Errorf("got port %s and sender %s, want 465 and the username", e.port, e.from.Address)
+	}
+}
@@ -0,0 +1,59 @@
+// Package publish sends a generated report to destinations such as chat
+// webhooks. Publishers register themselves by type, like providers do.
+package publish
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"worklog/internal/report"
+)
+
+// Publisher delivers a report to one destination.
+type Publisher interface {
+	// Configure reads settings through lookup, which takes upper-case keys
+	// such as "WEBHOOK_URL". It returns an error if a required setting is
+	// missing.
+	Configure(lookup func(string) string) error
+
+	// Payload renders what Publish would send, for --dry-run.
+	Payload(events []report.Event, since, until time.Time, opts report.Options) ([]byte, error)
+
+	Publish(ctx context.Context, events []report.Event, since, until time.Time, opts report.Options) error
+}
+
+// Factory returns a new, unconfigured publisher.
+type Factory func() Publisher
+
+var registry = make(map[string]Factory)
+
+// Register makes a publisher type available to config files and --post. It
+// panics if the type is registered twice.
+func Register(typ string, f Factory) {
+	if _, dup := registry[typ]; dup {
+		panic("publish: Register called twice for " + typ)
+	}
+	registry[typ] = f
+}
+
+// Types returns the registered publisher types, sorted.
+func Types() []string {
+	types := make([]string, 0, len(registry))
+	for typ := range registry {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New returns a new publisher of the given type.
+func New(typ string) (Publisher, error) {
+	f, ok := registry[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown publisher type %q: must be one of %s", typ, strings.Join(Types(), ", "))
+	}
+	return f(), nil
+}
@@ -0,0 +1,272 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"worklog/internal/report"
+)
+
+func init() {
+	Register("slack", func() Publisher { return &Slack{} })
+	Register("mattermost", func() Publisher { return &Mattermost{} })
+}
+
+// Block Kit limits, see https://api.slack.com/reference/block-kit/blocks.
+const (
+	maxBlocks      = 50
+	maxSectionText = 3000
+	maxContextText = 3000
+	maxHeaderText  = 150
+)
+
+// maxLinkText bounds the text of a link, so that a line with a link or two
+// fits a section and truncateLines never has to cut inside one.
+const maxLinkText = 300
+
+// Slack posts the report to a Slack incoming webhook as Block Kit: a section
+// per category, one linked line per event and a context block naming the
+// repo and source.
+type Slack struct {
+	webhook
+}
+
+func (s *Slack) Configure(lookup func(string) string) error {
+	return s.configure("slack", lookup)
+}
+
+func (s *Slack) Payload(events []report.Event, since, until time.Time, opts report.Options) ([]byte, error) {
+	msg := slackMessage{
+		Text:     report.Title(since, until, opts),
+		Channel:  s.channel,
+		Username: s.username,
+		Blocks:   slackBlocks(events, since, until, opts),
+	}
+	return json.MarshalIndent(msg, "", "  ")
+}
+
+func (s *Slack) Publish(ctx context.Context, events []report.Event, since, until time.Time, opts report.Options) error {
+	payload, err := s.Payload(events, since, until, opts)
+	if err != nil {
+		return err
+	}
+	return s.post(ctx, payload)
+}
+
+// Mattermost posts the report to a Mattermost incoming webhook. Mattermost
+// accepts Slack's payload fields but not Block Kit, so the message is the
+// Markdown report.
+type Mattermost struct {
+	webhook
+}
+
+func (m *Mattermost) Configure(lookup func(string) string) error {
+	return m.configure("mattermost", lookup)
+}
+
+func (m *Mattermost) Payload(events []report.Event, since, until time.Time, opts report.Options) ([]byte, error) {
+	// Mattermost does not render <details>, so categories are never folded.
+	md := opts
+	md.CollapseAfter = 0
+	text, err := report.Generate(events, since, until, "markdown", md)
+	if err != nil {
+		return nil, err
+	}
+	msg := slackMessage{Text: text, Channel: m.channel, Username: m.username}
+	return json.MarshalIndent(msg, "", "  ")
+}
+
+func (m *Mattermost) Publish(ctx context.Context, events []report.Event, since, until time.Time, opts report.Options) error {
+	payload, err := m.Payload(events, since, until, opts)
+	if err != nil {
+		return err
+	}
+	return m.post(ctx, payload)
+}
+
+// slackMessage is an incoming webhook payload. Text is the notification
+// fallback when blocks are given.
+type slackMessage struct {
+	Text     string       `json:"text"`
+	Channel  string       `json:"channel,omitempty"`
+	Username string       `json:"username,omitempty"`
+	Blocks   []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Elements []*slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func mrkdwn(text string) *slackText {
+	return &slackText{Type: "mrkdwn", Text: text}
+}
+
+func slackBlocks(events []report.Event, since, until time.Time, opts report.Options) []slackBlock {
+	blocks := []slackBlock{{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: truncate(report.Title(since, until, opts), maxHeaderText)},
+	}}
+	if notice := report.Notice(opts); notice != nil {
+		blocks = append(blocks, slackBlock{
+			Type: "context", Elements: []*slackText{mrkdwn(truncateLines(slackEscape(strings.Join(notice, "\n")), maxContextText))},
+		})
+	}
+
+	notes := slackNotes(opts.Notes)
+	groups := report.Categories(events)
+	if len(groups) == 0 {
+		blocks = append(blocks, slackBlock{Type: "section", Text: mrkdwn("No activity found for this period.")})
+		return limitBlocks(append(blocks, notes...), maxBlocks)
+	}
+
+	// Each event takes a section and a context block. Busy weeks exceed
+	// the block limit, in which case each category becomes one section
+	// listing its events with the repo and source inline.
+	n := len(blocks) + len(notes)
+	for _, g := range groups {
+		n += 1 + 2*len(g.Events)
+	}
+	if n > maxBlocks {
+		// Many notes leave no room for the categories, in which case
+		// the notes are cut instead.
+		limit := max(maxBlocks-len(blocks)-len(notes), 1)
+		blocks = append(blocks, compactSlackBlocks(groups, opts, limit)...)
+		return limitBlocks(append(blocks, notes...), maxBlocks)
+	}
+
+	for _, g := range groups {
+		blocks = append(blocks, slackBlock{Type: "section", Text: mrkdwn("*" + categoryHeader(g, opts) + "*")})
+		for _, e := range g.Events {
+			blocks = append(blocks,
+				slackBlock{Type: "section", Text: mrkdwn(truncateLines(slackLine(e)+slackCommits(e), maxSectionText))},
+				slackBlock{Type: "context", Elements: []*slackText{mrkdwn(slackContext(e))}},
+			)
+		}
+	}
+	return append(blocks, notes...)
+}
+
+// slackNotes renders each of the user's notes as a section.
+func slackNotes(notes []report.Note) []slackBlock {
+	var blocks []slackBlock
+	for _, n := range notes {
+		var b strings.Builder
+		b.WriteString("*" + slackEscape(n.Heading) + "*")
+		for _, item := range n.Items {
+			b.WriteString("\n• " + slackEscape(item))
+		}
+		blocks = append(blocks, slackBlock{Type: "section", Text: mrkdwn(truncateLines(b.String(), maxSectionText))})
+	}
+	return blocks
+}
+
+// compactSlackBlocks renders each category as one section, or several when
+// it exceeds the text limit, using at most limit blocks.
+func compactSlackBlocks(groups []report.CategoryGroup, opts report.Options, limit int) []slackBlock {
+	var blocks []slackBlock
+	for _, g := range groups {
+		var b strings.Builder
+		b.WriteString("*" + categoryHeader(g, opts) + "*")
+		for _, e := range g.Events {
+			line := fmt.Sprintf("\n• %s — %s%s", slackLine(e), slackContext(e), slackCommits(e))
+			if b.Len()+len(line) > maxSectionText {
+				blocks = append(blocks, slackBlock{Type: "section", Text: mrkdwn(truncateLines(b.String(), maxSectionText))})
+				b.Reset()
+				line = strings.TrimPrefix(line, "\n")
+			}
+			b.WriteString(line)
+		}
+		// A single line can still exceed the limit, e.g. a pull request
+		// with many commits.
+		blocks = append(blocks, slackBlock{Type: "section", Text: mrkdwn(truncateLines(b.String(), maxSectionText))})
+	}
+	return limitBlocks(blocks, limit)
+}
+
+// limitBlocks cuts blocks to at most limit, replacing the last one kept
+// with a note that the report was truncated. limit must be at least 1.
+func limitBlocks(blocks []slackBlock, limit int) []slackBlock {
+	if len(blocks) <= limit {
+		return blocks
+	}
+	return append(blocks[:limit-1:limit-1], slackBlock{
+		Type: "context", Elements: []*slackText{mrkdwn("Report truncated; see the full report for the rest.")},
+	})
+}
+
+func categoryHeader(g report.CategoryGroup, opts report.Options) string {
+	return report.CategoryHeader(report.EventCategory(g.Name), opts)
+}
+
+// slackLine renders the event's action and linked title.
+func slackLine(e report.Event) string {
+	line := report.ActionText(e) + " " + slackLink(e.Title, e.URL)
+	for _, t := range e.Tickets {
+		line += " → " + slackLink(t.Key, t.URL)
+	}
+	return line
+}
+
+// slackCommits renders the commits nested under a pull request, one
+// indented line each.
+func slackCommits(e report.Event) string {
+	var b strings.Builder
+	for _, c := range e.Commits {
+		fmt.Fprintf(&b, "\n      `%s` %s", report.ShortSHA(c.SHA), slackLink(c.Title, c.URL))
+	}
+	return b.String()
+}
+
+func slackContext(e report.Event) string {
+	return fmt.Sprintf("`%s` · %s", slackEscape(e.Repo), slackEscape(report.SourceLabel(e)))
+}
+
+func slackLink(text, url string) string {
+	if url == "" {
+		return slackEscape(text)
+	}
+	return fmt.Sprintf("<%s|%s>", url, slackEscape(truncate(text, maxLinkText)))
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackEscape escapes the characters Slack reserves for links and mentions.
+func slackEscape(s string) string {
+	return slackEscaper.Replace(s)
+}
+
+// truncateLines cuts mrkdwn text to at most n characters by dropping whole
+// lines from the end, so that no link or escape is split, and ends it with a
+// line "…" in their place. A single line too long is cut as plain text.
+func truncateLines(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+		if cut := strings.Join(lines, "\n") + "\n…"; utf8.RuneCountInString(cut) <= n {
+			return cut
+		}
+	}
+	return truncate(lines[0], n)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,243 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"worklog/internal/report"
+	"worklog/internal/testutil"
+)
+
+// receiver is an incoming webhook that records the requests it is sent and
+// answers with status and body.
+type receiver struct {
+	*httptest.Server
+	requests []*http.Request
+	payloads [][]byte
+}
+
+func newReceiver(t *testing.T, status int, body string) *receiver {
+	t.Helper()
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		payload, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("reading payload: %v", err)
+		}
+		r.requests = append(r.requests, req)
+		r.payloads = append(r.payloads, payload)
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func lookup(settings map[string]string) func(string) string {
+	return func(key string) string { return settings[key] }
+}
+
+func pullRequests(n int) []report.Event {
+	var events []report.Event
+	for i := range n {
+		events = append(events, report.Event{
+			Category:  report.CategoryPR,
+			Action:    "opened",
+			Title:     fmt.Sprintf("#%d Change %d", i+1, i+1),
+			URL:       fmt.Sprintf("https://github.com/acme/api/pull/%d", i+1),
+			Repo:      "acme/api",
+			Source:    "github",
+			CreatedAt: testutil.Since.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return events
+}
+
+func publishSlack(t *testing.T, events []report.Event, opts report.Options) slackMessage {
+	t.Helper()
+	r := newReceiver(t, http.StatusOK, "ok")
+	s := &Slack{}
+	err := s.Configure(lookup(map[string]string{"WEBHOOK_URL": r.URL, "CHANNEL": "#standup", "USERNAME": "worklog"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Publish(context.Background(), events, testutil.Since, testutil.Until, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(r.requests))
+	}
+	req := r.requests[0]
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("got %s with content type %q, want a JSON POST", req.Method, req.Header.Get("Content-Type"))
+	}
+	var msg slackMessage
+	if err := json.Unmarshal(r.payloads[0], &msg); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	return msg
+}
+
+func checkLimits(t *testing.T, msg slackMessage) {
+	t.Helper()
+	if len(msg.Blocks) > maxBlocks {
+		t.Errorf("got %d blocks, want at most %d", len(msg.Blocks), maxBlocks)
+	}
+	for i, b := range msg.Blocks {
+		texts := b.Elements
+		if b.Type == "section" {
+			texts = append(texts, b.Text)
+		}
+		for _, text := range texts {
+			if n := utf8.RuneCountInString(text.Text); n > maxSectionText {
+				t.Errorf("block %d has %d characters, want at most %d", i, n, maxSectionText)
+			}
+			// Text is escaped, so any < or > belongs to a link.
+			if strings.Count(text.Text, "<") != strings.Count(text.Text, ">") {
+				t.Errorf("block %d has a link cut short: %q", i, text.Text)
+			}
+		}
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	events := pullRequests(2)
+	events[0].Title = "#1 Escape <b> & friends"
+	msg := publishSlack(t, events, report.Options{})
+
+	if msg.Channel != "#standup" || msg.Username != "worklog" {
+		t.Errorf("got channel %q and username %q", msg.Channel, msg.Username)
+	}
+	if msg.Text != report.Title(testutil.Since, testutil.Until, report.Options{}) {
+		t.Errorf("got fallback text %q", msg.Text)
+	}
+	var types []string
+	for _, b := range msg.Blocks {
+		types = append(types, b.Type)
+	}
+	want := "header section section context section context"
+	if got := strings.Join(types, " "); got != want {
+		t.Fatalf("got blocks %q, want %q", got, want)
+	}
+	line := msg.Blocks[4].Text.Text
+	if want := "<https://github.com/acme/api/pull/1|#1 Escape &lt;b&gt; &amp; friends>"; !strings.Contains(line, want) {
+		t.Errorf("got line %q, want it to contain %q", line, want)
+	}
+	if got := msg.Blocks[5].Elements[0].Text; got != "`acme/api` · github" {
+		t.Errorf("got context %q", got)
+	}
+}
+
+func TestSlackBlockLimits(t *testing.T) {
+	manyNotes := make([]report.Note, 60)
+	for i := range manyNotes {
+		manyNotes[i] = report.Note{Heading: fmt.Sprintf("Note %d", i), Items: []string{"item"}}
+	}
+	longTitle := pullRequests(1)
+	longTitle[0].Title = "#1 " + strings.Repeat("long ", 1000)
+	manyCommits := pullRequests(30)
+	for i := range 80 {
+		manyCommits[0].Commits = append(manyCommits[0].Commits, report.Event{
+			Title: strings.Repeat("commit ", 10), SHA: fmt.Sprintf("%040d", i),
+		})
+	}
+
+	manyWarnings := make([]string, 100)
+	for i := range manyWarnings {
+		manyWarnings[i] = fmt.Sprintf("github: more than 50 branches of acme/repo-%d were committed to in the range", i)
+	}
+
+	tests := []struct {
+		name     string
+		events   []report.Event
+		notes    []report.Note
+		warnings []string
+	}{
+		{"one event", pullRequests(1), nil, nil},
+		{"busy week", pullRequests(200), nil, nil},
+		{"busy week with many notes", pullRequests(200), manyNotes, nil},
+		{"no activity with many notes", nil, manyNotes, nil},
+		{"long title", longTitle, nil, nil},
+		{"many commits", manyCommits, nil, nil},
+		{"many warnings", pullRequests(1), nil, manyWarnings},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := publishSlack(t, tt.events, report.Options{Notes: tt.notes, Warnings: tt.warnings})
+			checkLimits(t, msg)
+		})
+	}
+}
+
+func TestSlackCompactTruncated(t *testing.T) {
+	events := pullRequests(500)
+	for i := range events {
+		events[i].Title += strings.Repeat(" and more", 50)
+	}
+	msg := publishSlack(t, events, report.Options{})
+	checkLimits(t, msg)
+	last := msg.Blocks[len(msg.Blocks)-1]
+	if last.Type != "context" || !strings.Contains(last.Elements[0].Text, "truncated") {
+		t.Errorf("got last block %+v, want the truncation note", last)
+	}
+}
+
+func TestMattermostPayload(t *testing.T) {
+	r := newReceiver(t, http.StatusOK, "ok")
+	m := &Mattermost{}
+	if err := m.Configure(lookup(map[string]string{"WEBHOOK_URL": r.URL})); err != nil {
+		t.Fatal(err)
+	}
+	opts := report.Options{CollapseAfter: 1}
+	if err := m.Publish(context.Background(), pullRequests(3), testutil.Since, testutil.Until, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.payloads) != 1 {
+		t.Fatalf("got %d requests, want 1", len(r.payloads))
+	}
+	var msg map[string]any
+	if err := json.Unmarshal(r.payloads[0], &msg); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	if _, ok := msg["blocks"]; ok {
+		t.Error("payload has blocks, which Mattermost does not render")
+	}
+	text, _ := msg["text"].(string)
+	if !strings.Contains(text, `[\#3 Change 3](https://github.com/acme/api/pull/3)`) {
+		t.Errorf("text is not the Markdown report:\n%s", text)
+	}
+	if strings.Contains(text, "<details>") {
+		t.Errorf("text has a collapsed category:\n%s", text)
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	publishers := map[string]Publisher{"slack": &Slack{}, "mattermost": &Mattermost{}}
+	for name, p := range publishers {
+		t.Run(name, func(t *testing.T) {
+			r := newReceiver(t, http.StatusForbidden, "invalid_token\n")
+			if err := p.Configure(lookup(map[string]string{"WEBHOOK_URL": r.URL})); err != nil {
+				t.Fatal(err)
+			}
+			err := p.Publish(context.Background(), pullRequests(1), testutil.Since, testutil.Until, report.Options{})
+			if err == nil || err.Error() != "webhook: 403 Forbidden: invalid_token" {
+				t.Errorf("got error %v, want the status and body", err)
+			}
+		})
+	}
+}
+
+func TestWebhookConfigure(t *testing.T) {
+	err := (&Slack{}).Configure(lookup(nil))
+	if err == nil || !strings.Contains(err.Error(), "SLACK_WEBHOOK_URL") {
+		t.Errorf("got error %v, want one naming SLACK_WEBHOOK_URL", err)
+	}
+}
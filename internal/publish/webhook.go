@@ -0,0 +1,54 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// webhook holds the settings shared by Slack and Mattermost incoming
+// webhooks. Channel and username override the webhook's defaults where the
+// server allows it.
+type webhook struct {
+	url      string
+	channel  string
+	username string
+}
+
+func (w *webhook) configure(typ string, lookup func(string) string) error {
+	w.url = lookup("WEBHOOK_URL")
+	w.channel = lookup("CHANNEL")
+	w.username = lookup("USERNAME")
+	if w.url == "" {
+		return fmt.Errorf("webhook_url or %s_WEBHOOK_URL must be set", strings.ToUpper(typ))
+	}
+	return nil
+}
+
+// post sends payload to the webhook. Both Slack and Mattermost answer with a
+// plain-text body, which is included in the error on failure.
+func (w *webhook) post(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return fmt.Errorf("webhook: %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("webhook: %s", resp.Status)
+	}
+	return nil
+}
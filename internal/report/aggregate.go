@@ -0,0 +1,110 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Aggregate merges events that repeat the same action on the same item, such
+// as five comments on one pull request, into a single event. The merged
+// event is the newest one, with every merged event, itself included, in its
+// Occurrences, newest first. Events match when their category, action, repo,
+// title and source agree. Commits are never merged, as each is a separate
+// change even when two share a message, and neither are the categories in
+// skip nor issue transitions, whose actions such as "moved to Done" have an
+// object of their own that a count would not read well after. Logged work is
+// merged with its time summed.
+func Aggregate(events []Event, skip map[EventCategory]bool) []Event {
+	groups := make(map[string][]int)
+	var keys []string
+	for i, e := range events {
+		if e.Category == CategoryCommit || e.Category == CategoryTransition || skip[e.Category] {
+			keys = append(keys, "")
+			continue
+		}
+		key := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", e.Category, e.Action, e.Repo, e.Title, SourceLabel(e))
+		groups[key] = append(groups[key], i)
+		keys = append(keys, key)
+	}
+
+	var out []Event
+	for i, e := range events {
+		group := groups[keys[i]]
+		if keys[i] == "" || len(group) == 1 {
+			out = append(out, e)
+			continue
+		}
+		if group[0] != i {
+			continue // merged into the group's first event
+		}
+		occurrences := make([]Event, len(group))
+		for j, k := range group {
+			occurrences[j] = events[k]
+		}
+		sort.SliceStable(occurrences, func(a, b int) bool {
+			return occurrences[a].CreatedAt.After(occurrences[b].CreatedAt)
+		})
+		merged := occurrences[0]
+		merged.Occurrences = occurrences
+		out = append(out, merged)
+	}
+	return out
+}
+
+// Count returns the number of times the event's action happened: the
+// number of merged events for an aggregated event, else 1.
+func (e Event) Count() int {
+	if len(e.Occurrences) == 0 {
+		return 1
+	}
+	return len(e.Occurrences)
+}
+
+// FirstAt returns the time of the earliest merged event, or CreatedAt for
+// an event that was not aggregated.
+func (e Event) FirstAt() time.Time {
+	if len(e.Occurrences) == 0 {
+		return e.CreatedAt
+	}
+	return e.Occurrences[len(e.Occurrences)-1].CreatedAt
+}
+
+// TotalTimeSpent returns the work logged by the event and, for an
+// aggregated event, by every merged event.
+func (e Event) TotalTimeSpent() time.Duration {
+	if len(e.Occurrences) == 0 {
+		return e.TimeSpent
+	}
+	var total time.Duration
+	for _, o := range e.Occurrences {
+		total += o.TimeSpent
+	}
+	return total
+}
+
+// ActionText returns the capitalized action that starts an event's line,
+// with the count for aggregated events, e.g. "Commented 5× on", or the total
+// time for logged work, e.g. "Logged 3h 30m on".
+func ActionText(e Event) string {
+	if d := e.TotalTimeSpent(); d > 0 {
+		return fmt.Sprintf("%s %s on", Capitalize(e.Action), formatTimeSpent(d))
+	}
+	if n := e.Count(); n > 1 {
+		return fmt.Sprintf("%s %d× on", Capitalize(e.Action), n)
+	}
+	return Capitalize(e.Action)
+}
+
+// formatTimeSpent formats d in hours and minutes as Jira does, e.g. "2h",
+// "1h 30m" or "45m".
+func formatTimeSpent(d time.Duration) string {
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	}
+	return fmt.Sprintf("%dh %dm", h, m)
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"sort"
+	"strings"
+)
+
+// CommitModes lists the ways GroupCommits can show commits that belong to a
+// pull request: nested under it, flat in the Commits category, or hidden.
+var CommitModes = []string{"nested", "flat", "hidden"}
+
+// GroupCommits links commit events to the pull request they belong to: one
+// in the same repo that lists the commit's SHA, or whose source branch the
+// commit was pushed to. In "nested" mode linked commits move into the
+// Commits of the pull request's latest event, oldest first; in "hidden" mode
+// they are dropped. Commits that match no pull request stay in the Commits
+// category, and "flat" mode returns events unchanged.
+func GroupCommits(events []Event, mode string) []Event {
+	if mode == "flat" {
+		return events
+	}
+
+	// A pull request can appear several times, e.g. opened and merged. Its
+	// commits go under the latest event, and the SHAs and branch known to
+	// any of them are used to link commits.
+	latest := make(map[string]int)
+	for i, e := range events {
+		if e.Category != CategoryPR {
+			continue
+		}
+		key := pullRequestKey(e)
+		if j, ok := latest[key]; !ok || e.CreatedAt.After(events[j].CreatedAt) {
+			latest[key] = i
+		}
+	}
+	if len(latest) == 0 {
+		return events
+	}
+
+	bySHA := make(map[string]int)
+	byBranch := make(map[string]int)
+	for _, e := range events {
+		if e.Category != CategoryPR {
+			continue
+		}
+		pr := latest[pullRequestKey(e)]
+		for _, sha := range e.CommitSHAs {
+			bySHA[e.Repo+"\x00"+sha] = pr
+		}
+		if e.Branch == "" {
+			continue
+		}
+		// A branch reused by two pull requests cannot tell their commits
+		// apart, so it links to neither.
+		key := e.Repo + "\x00" + e.Branch
+		if j, ok := byBranch[key]; ok && j != pr {
+			byBranch[key] = -1
+		} else {
+			byBranch[key] = pr
+		}
+	}
+
+	nested := make(map[int][]Event)
+	position := make(map[int]int) // index in events -> index in out
+	var out []Event
+	for i, e := range events {
+		if e.Category == CategoryCommit {
+			pr, ok := bySHA[e.Repo+"\x00"+e.SHA]
+			if !ok || e.SHA == "" {
+				pr, ok = byBranch[e.Repo+"\x00"+e.Branch]
+				ok = ok && e.Branch != "" && pr >= 0
+			}
+			if ok {
+				nested[pr] = append(nested[pr], e)
+				continue
+			}
+		}
+		position[i] = len(out)
+		out = append(out, e)
+	}
+	if mode == "hidden" {
+		return out
+	}
+
+	for pr, commits := range nested {
+		sort.SliceStable(commits, func(a, b int) bool {
+			return commits[a].CreatedAt.Before(commits[b].CreatedAt)
+		})
+		out[position[pr]].Commits = commits
+	}
+	return out
+}
+
+// PullRequestCommits is what LinkPullRequests needs to know of a pull
+// request. Branch is its source branch, left empty when the pull request
+// comes from another repository, as pushes to a fork's branch of the same
+// name are not the pull request's. SHAs are its commits, including the
+// merge or squash commit once merged.
+type PullRequestCommits struct {
+	Branch string
+	SHAs   []string
+}
+
+// LinkPullRequests sets the Branch and CommitSHAs of the pull request events,
+// so that GroupCommits can group their commits under them. read is called
+// once per pull request with its first event. Pull requests it fails for are
+// left unlinked, and the last error is returned.
+func LinkPullRequests(events []Event, read func(e Event) (PullRequestCommits, error)) error {
+	prs := make(map[string]*PullRequestCommits)
+	var lastErr error
+	for i, e := range events {
+		if e.Category != CategoryPR {
+			continue
+		}
+		key := pullRequestKey(e)
+		pr, ok := prs[key]
+		if !ok {
+			linked, err := read(e)
+			if err != nil {
+				lastErr = err
+			} else {
+				pr = &linked
+			}
+			prs[key] = pr
+		}
+		if pr != nil {
+			events[i].Branch = pr.Branch
+			events[i].CommitSHAs = pr.SHAs
+		}
+	}
+	return lastErr
+}
+
+// pullRequestKey identifies the pull request an event is about.
+func pullRequestKey(e Event) string {
+	if e.URL != "" {
+		return e.URL
+	}
+	return e.Source + "\x00" + e.Account + "\x00" + e.Repo + "\x00" + e.Title
+}
+
+// ShortSHA abbreviates a commit SHA for display.
+func ShortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// FirstLine returns the first line of s, such as the subject of a commit
+// message.
+func FirstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -1,6 +1,10 @@
 package report
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
 
 type EventCategory string
 
@@ -10,17 +14,116 @@ const (
 	CategoryReview        EventCategory = "Code Reviews"
 	CategoryReviewComment EventCategory = "Review Comments"
 	CategoryIssue         EventCategory = "Issues"
+	CategoryTransition    EventCategory = "Issue Transitions"
 	CategoryComment       EventCategory = "Comments"
 	CategoryPipeline      EventCategory = "CI Pipeline Failures"
 	CategoryPendingReview EventCategory = "Pending Reviews"
 )
 
+var categorySlugs = map[EventCategory]string{
+	CategoryCommit:        "commits",
+	CategoryPR:            "pull_requests",
+	CategoryReview:        "reviews",
+	CategoryReviewComment: "review_comments",
+	CategoryIssue:         "issues",
+	CategoryTransition:    "transitions",
+	CategoryComment:       "comments",
+	CategoryPipeline:      "pipelines",
+	CategoryPendingReview: "pending_reviews",
+}
+
+// Slug returns the short identifier used for the category in config files
+// and flags, e.g. "pull_requests".
+func (c EventCategory) Slug() string {
+	return categorySlugs[c]
+}
+
+// ParseCategory returns the category identified by slug.
+func ParseCategory(slug string) (EventCategory, bool) {
+	for cat, s := range categorySlugs {
+		if s == slug {
+			return cat, true
+		}
+	}
+	return "", false
+}
+
+// CategorySlugs returns the slugs of all categories in report order.
+func CategorySlugs() []string {
+	slugs := make([]string, len(categoryOrder))
+	for i, cat := range categoryOrder {
+		slugs[i] = cat.Slug()
+	}
+	return slugs
+}
+
 type Event struct {
 	Category  EventCategory
 	Action    string
 	Title     string
 	URL       string
 	Repo      string
-	Source    string // "github" or "gitlab"
+	Source    string // provider name, e.g. "github" or "gitlab"
+	Account   string // account label when the provider has several, else empty
+	SHA       string // commit SHA, set on commit events when known
+	Ticket    string // issue-tracker key such as "ABC-123", set on tracker events
+	Tickets   []TicketRef
 	CreatedAt time.Time
+	TimeSpent time.Duration // work logged by a worklog event, whose action is "logged"
+
+	// Branch is the branch a commit was pushed to, or the source branch of
+	// a pull request, when known. CommitSHAs lists a pull request's commits,
+	// including its merge or squash commit, when the provider reports them.
+	// GroupCommits uses both to move commit events into Commits.
+	Branch     string
+	CommitSHAs []string
+	Commits    []Event
+
+	// Occurrences holds the events Aggregate merged into this one, newest
+	// first, including this one. It is empty for a single event.
+	Occurrences []Event
+
+	// Rank is the position the user gave the event in worklog review.
+	// Ranked events come first in their category, in rank order.
+	Rank int
+}
+
+// TicketRef links an event to an issue-tracker ticket referenced in its title.
+type TicketRef struct {
+	Key string
+	URL string
+}
+
+// ID returns a stable identifier for the event. It is derived from the
+// fields that identify the activity rather than describe it, so it survives
+// edits to titles, and for commits only from the SHA, so a local commit keeps
+// its ID once it is pushed.
+func (e Event) ID() string {
+	fields := []string{string(e.Category), e.Source, e.Account, e.Repo, e.SHA}
+	if e.SHA == "" {
+		fields = append(fields, e.Action, e.URL, e.CreatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	return hashFields(fields)
+}
+
+// LineKey identifies the report line the event is shown on, so that a line
+// hidden in worklog review stays hidden in later reports. Unlike ID, it is
+// the same for every event Aggregate merges into the line, whichever is the
+// newest, as it is derived from the fields they are merged on rather than
+// from when the activity happened. Commits are identified by SHA.
+func (e Event) LineKey() string {
+	fields := []string{string(e.Category), e.Source, e.Account, e.Repo, e.SHA}
+	if e.SHA == "" {
+		fields = append(fields, e.Action, e.Title)
+	}
+	return hashFields(fields)
+}
+
+func hashFields(fields []string) string {
+	h := sha256.New()
+	for _, s := range fields {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
 }
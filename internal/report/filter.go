@@ -0,0 +1,122 @@
+package report
+
+import (
+	"path"
+	"strings"
+)
+
+// FilterCategories drops events whose category is disabled.
+func FilterCategories(events []Event, disabled map[EventCategory]bool) []Event {
+	if len(disabled) == 0 {
+		return events
+	}
+	var out []Event
+	for _, e := range events {
+		if !disabled[e.Category] {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// RepoFilter selects events by repository and organization. Patterns use
+// path.Match syntax: repo patterns such as "acme/*" match "owner/repo"
+// names, and org patterns match the part of the name before the first slash
+// (the whole name for repos without one, such as Jira project keys).
+//
+// A repo passes if it matches any include pattern, when there are any, and
+// no exclude pattern. Include repo and org patterns are alternatives, so
+// including org "acme" and repo "me/tool" keeps both.
+type RepoFilter struct {
+	IncludeRepos []string
+	ExcludeRepos []string
+	IncludeOrgs  []string
+	ExcludeOrgs  []string
+}
+
+// Empty reports whether the filter lets every repo through.
+func (f RepoFilter) Empty() bool {
+	return len(f.IncludeRepos) == 0 && len(f.ExcludeRepos) == 0 &&
+		len(f.IncludeOrgs) == 0 && len(f.ExcludeOrgs) == 0
+}
+
+// Match reports whether repo passes the filter.
+func (f RepoFilter) Match(repo string) bool {
+	org := Org(repo)
+	if len(f.IncludeRepos) > 0 || len(f.IncludeOrgs) > 0 {
+		if !matchAny(f.IncludeRepos, repo) && !matchAny(f.IncludeOrgs, org) {
+			return false
+		}
+	}
+	return !matchAny(f.ExcludeRepos, repo) && !matchAny(f.ExcludeOrgs, org)
+}
+
+// MatchOrg reports whether some repo in org could pass the filter. Providers
+// use it to skip whole organizations, workspaces or groups.
+func (f RepoFilter) MatchOrg(org string) bool {
+	if matchAny(f.ExcludeOrgs, org) {
+		return false
+	}
+	if len(f.IncludeRepos) > 0 || len(f.IncludeOrgs) > 0 {
+		if matchAny(f.IncludeOrgs, org) {
+			return true
+		}
+		for _, p := range f.IncludeRepos {
+			if ok, _ := path.Match(Org(p), org); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// LiteralIncludes returns the include patterns when none of them contains
+// glob syntax, so that a provider can turn them into exact query qualifiers.
+// ok is false when there are no includes or some of them are globs.
+func (f RepoFilter) LiteralIncludes() (orgs, repos []string, ok bool) {
+	if len(f.IncludeOrgs) == 0 && len(f.IncludeRepos) == 0 {
+		return nil, nil, false
+	}
+	for _, p := range append(f.IncludeOrgs, f.IncludeRepos...) {
+		if !IsLiteral(p) {
+			return nil, nil, false
+		}
+	}
+	return f.IncludeOrgs, f.IncludeRepos, true
+}
+
+// IsLiteral reports whether pattern contains no glob syntax.
+func IsLiteral(pattern string) bool {
+	return !strings.ContainsAny(pattern, `*?[\`)
+}
+
+// Org returns the organization part of a repo name: everything before the
+// first slash, or the whole name if it has none.
+func Org(repo string) string {
+	org, _, _ := strings.Cut(repo, "/")
+	return org
+}
+
+// FilterRepos keeps the events whose repo passes f.
+func FilterRepos(events []Event, f RepoFilter) []Event {
+	if f.Empty() {
+		return events
+	}
+	var out []Event
+	for _, e := range events {
+		if f.Match(e.Repo) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package report
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Groupings lists the keys a report can be grouped by.
+var Groupings = []string{"category", "day", "repo", "source"}
+
+// currentKey is the day group of events that describe the present rather
+// than a day in the range, such as pending reviews.
+const currentKey = "current"
+
+// Group is a set of events that share a grouping key, divided further into
+// Groups when the report is grouped by two keys.
+type Group struct {
+	Key    string // stable identifier: category slug, "2006-01-02", repo or source label
+	Name   string // display name: category header, "Monday, Jan 2", repo or source label
+	Events []Event
+	Groups []Group
+}
+
+// ParseGroupBy parses a comma-separated list of one or two groupings, such
+// as "day,category". Grouping by anything but category alone adds category
+// as the second level, so that lines keep their context.
+func ParseGroupBy(s string) ([]string, error) {
+	var by []string
+	for _, key := range strings.Split(s, ",") {
+		key = strings.TrimSpace(key)
+		if !slices.Contains(Groupings, key) {
+			return nil, fmt.Errorf("unknown grouping %q: must be one of %s", key, strings.Join(Groupings, ", "))
+		}
+		if slices.Contains(by, key) {
+			return nil, fmt.Errorf("grouping %q is given twice", key)
+		}
+		by = append(by, key)
+	}
+	switch {
+	case len(by) > 2:
+		return nil, fmt.Errorf("at most two groupings can be given, e.g. \"day,category\"")
+	case len(by) == 1 && by[0] != "category":
+		by = append(by, "category")
+	}
+	return by, nil
+}
+
+// GroupEvents groups events by the keys in by, the first key outermost.
+// Categories are in report order, days newest first followed by the events
+// that describe the present, and repos and sources sorted by name. Events
+// within a group are in report order. An empty by groups by category.
+func GroupEvents(events []Event, by []string, opts Options) []Group {
+	if len(by) == 0 {
+		by = []string{"category"}
+	}
+	catIndex := make(map[EventCategory]int)
+	for i, cat := range categoryOrder {
+		catIndex[cat] = i
+	}
+
+	var groups []Group
+	index := make(map[string]int)
+	order := make(map[string]int) // sort rank of categories
+	for _, e := range sortedEvents(events) {
+		var key, name string
+		switch by[0] {
+		case "day":
+			if slices.Contains(snapshotCategories, e.Category) {
+				key, name = currentKey, "Current"
+				if opts.Offline {
+					name = "As of last sync"
+				}
+				break
+			}
+			t := e.CreatedAt
+			day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+			key, name = day.Format("2006-01-02"), day.Format("Monday, Jan 2")
+		case "repo":
+			key, name = e.Repo, e.Repo
+		case "source":
+			key, name = SourceLabel(e), SourceLabel(e)
+		default:
+			key, name = e.Category.Slug(), CategoryHeader(e.Category, opts)
+			order[key] = catIndex[e.Category]
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, Group{Key: key, Name: name})
+		}
+		groups[i].Events = append(groups[i].Events, e)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		switch by[0] {
+		case "day":
+			if groups[i].Key == currentKey || groups[j].Key == currentKey {
+				return groups[j].Key == currentKey && groups[i].Key != currentKey
+			}
+			return groups[i].Key > groups[j].Key
+		case "repo", "source":
+			return groups[i].Key < groups[j].Key
+		default:
+			return order[groups[i].Key] < order[groups[j].Key]
+		}
+	})
+	if len(by) > 1 {
+		for i := range groups {
+			groups[i].Groups = GroupEvents(groups[i].Events, by[1:], opts)
+		}
+	}
+	return groups
+}
+
+// leafEvents returns the events of groups in group order.
+func leafEvents(groups []Group) []Event {
+	var events []Event
+	for _, g := range groups {
+		if g.Groups != nil {
+			events = append(events, leafEvents(g.Groups)...)
+		} else {
+			events = append(events, g.Events...)
+		}
+	}
+	return events
+}
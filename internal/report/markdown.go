@@ -0,0 +1,89 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func generateMarkdown(events []Event, since, until time.Time, opts Options) string {
+	var b strings.Builder
+
+	b.WriteString("## " + markdownEscape(Title(since, until, opts)) + "\n\n")
+	if notice := Notice(opts); notice != nil {
+		b.WriteString("> " + strings.Join(notice, "\n> ") + "\n\n")
+	}
+
+	for _, g := range GroupEvents(events, opts.GroupBy, opts) {
+		writeMarkdownGroup(&b, g, "###", opts)
+	}
+
+	if len(events) == 0 {
+		b.WriteString("_No activity found for this period._\n")
+		if len(opts.Notes) > 0 {
+			b.WriteString("\n")
+		}
+	}
+	writeMarkdownNotes(&b, opts.Notes)
+
+	return b.String()
+}
+
+// writeMarkdownGroup writes a group under a heading of the given level,
+// with its subgroups one level deeper.
+func writeMarkdownGroup(b *strings.Builder, g Group, heading string, opts Options) {
+	b.WriteString(fmt.Sprintf("%s %s\n\n", heading, markdownEscape(g.Name)))
+	if g.Groups != nil {
+		for _, sub := range g.Groups {
+			writeMarkdownGroup(b, sub, heading+"#", opts)
+		}
+		return
+	}
+
+	collapse := opts.CollapseAfter > 0 && len(g.Events) > opts.CollapseAfter
+	if collapse {
+		b.WriteString(fmt.Sprintf("<details>\n<summary>%d items</summary>\n\n", len(g.Events)))
+	}
+	for _, e := range g.Events {
+		b.WriteString(fmt.Sprintf("- %s %s `%s` · %s%s\n",
+			ActionText(e), markdownLink(e.Title, e.URL), e.Repo, SourceLabel(e), markdownTickets(e.Tickets)))
+		for _, c := range e.Commits {
+			b.WriteString(fmt.Sprintf("  - %s `%s`\n", markdownLink(c.Title, c.URL), ShortSHA(c.SHA)))
+		}
+	}
+	if collapse {
+		b.WriteString("\n</details>\n")
+	}
+	b.WriteString("\n")
+}
+
+// markdownLink renders text as a link to url, or as plain text if the event
+// has no URL.
+func markdownLink(text, url string) string {
+	if url == "" {
+		return markdownEscape(text)
+	}
+	return fmt.Sprintf("[%s](%s)", markdownEscape(text), url)
+}
+
+func markdownTickets(tickets []TicketRef) string {
+	if len(tickets) == 0 {
+		return ""
+	}
+	links := make([]string, len(tickets))
+	for i, t := range tickets {
+		links[i] = markdownLink(t.Key, t.URL)
+	}
+	return " → " + strings.Join(links, ", ")
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// markdownEscape escapes characters that would otherwise turn titles into
+// formatting, links or HTML.
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// StandupSections are the headings of the notes a standup usually has, in
+// the order they are listed.
+var StandupSections = []string{"Yesterday", "Today", "Blockers"}
+
+// Note is a section the user wrote rather than fetched, such as
+// "Blockers", listed after the activity. Items are its lines.
+type Note struct {
+	Heading string
+	Items   []string
+}
+
+// Key returns the note's identifier in JSON output, the heading in lower
+// case with other characters than letters and digits replaced by dashes,
+// e.g. "plan-for-today".
+func (n Note) Key() string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(n.Heading) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// AddNote appends item to the note with the given heading, adding the note
+// if there is none yet.
+func AddNote(notes []Note, heading, item string) []Note {
+	for i := range notes {
+		if notes[i].Heading == heading {
+			notes[i].Items = append(notes[i].Items, item)
+			return notes
+		}
+	}
+	return append(notes, Note{Heading: heading, Items: []string{item}})
+}
+
+func writeTextNotes(b *strings.Builder, notes []Note) {
+	for _, n := range notes {
+		b.WriteString(fmt.Sprintf("%s:\n", n.Heading))
+		for _, item := range n.Items {
+			b.WriteString(fmt.Sprintf("  - %s\n", item))
+		}
+		b.WriteString("\n")
+	}
+}
+
+func writeMarkdownNotes(b *strings.Builder, notes []Note) {
+	for _, n := range notes {
+		b.WriteString(fmt.Sprintf("### %s\n\n", markdownEscape(n.Heading)))
+		for _, item := range n.Items {
+			b.WriteString(fmt.Sprintf("- %s\n", markdownEscape(item)))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// ParseNotes reads notes in the format of the text report: a heading line
+// ending in a colon, followed by its items, each on a line of its own and
+// optionally starting with "-" or "*". Blank lines and lines starting with
+// "#" are ignored, as are headings without items.
+func ParseNotes(r io.Reader) ([]Note, error) {
+	var notes []Note
+	heading := ""
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		raw := sc.Text()
+		line := strings.TrimSpace(raw)
+		bullet := strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || line == "-" || line == "*"
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case !bullet && strings.HasSuffix(line, ":") && !strings.HasPrefix(raw, " ") && !strings.HasPrefix(raw, "\t"):
+			heading = strings.TrimSpace(strings.TrimSuffix(line, ":"))
+			if heading == "" {
+				return nil, fmt.Errorf("line %d: empty heading", n)
+			}
+		case heading == "":
+			return nil, fmt.Errorf("line %d: %q is not under a heading such as \"Today:\"", n, line)
+		default:
+			item := line
+			if bullet {
+				item = strings.TrimSpace(line[1:])
+			}
+			if item != "" {
+				notes = AddNote(notes, heading, item)
+			}
+		}
+	}
+	return notes, sc.Err()
+}
+
+// FormatNotes writes notes in the format ParseNotes reads.
+func FormatNotes(notes []Note) string {
+	var b strings.Builder
+	writeTextNotes(&b, notes)
+	return b.String()
+}
@@ -3,9 +3,12 @@ package report
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"text/template"
 	"time"
 	"unicode"
 )
@@ -15,136 +18,501 @@ var categoryOrder = []EventCategory{
 	CategoryReview,
 	CategoryReviewComment,
 	CategoryIssue,
+	CategoryTransition,
 	CategoryComment,
 	CategoryCommit,
 	CategoryPipeline,
 	CategoryPendingReview,
 }
 
-func Generate(events []Event, since, until time.Time, format string) string {
+// Formats lists the output formats accepted by Generate.
+var Formats = []string{"text", "table", "json", "markdown", "template"}
+
+// Options tune how a report is rendered. The zero value gives the default
+// layout.
+type Options struct {
+	// CollapseAfter wraps markdown categories with more than this many
+	// events in a collapsible <details> block. Zero disables collapsing.
+	CollapseAfter int
+
+	// Template renders the "template" format; see TemplateData for the
+	// data passed to it.
+	Template *template.Template
+
+	// GroupBy lists the keys events are grouped by, outermost first, as
+	// returned by ParseGroupBy. Empty groups by category.
+	GroupBy []string
+
+	// Sprint names the sprint the report covers, if it was selected as one.
+	// It replaces "Standup Report" in the heading.
+	Sprint string
+
+	// Offline marks a report generated from the local cache without
+	// contacting the providers. Syncs lists when each account was last
+	// fetched.
+	Offline bool
+	Syncs   []SyncStatus
+
+	// Warnings explain gaps in the report, such as ranges a provider
+	// could not fully cover.
+	Warnings []string
+
+	// Notes are the user's own sections, listed after the activity.
+	Notes []Note
+}
+
+// SyncStatus is the time an account's events were last fetched.
+type SyncStatus struct {
+	Account  string    // e.g. "github" or "github:work"
+	SyncedAt time.Time // zero if the account was never synced
+}
+
+// snapshotCategories describe the present rather than the report range, so
+// they are stale in offline reports.
+var snapshotCategories = []EventCategory{CategoryPendingReview}
+
+// StaleCategories returns the categories that may be out of date in the
+// report: none, unless it is offline.
+func StaleCategories(opts Options) []EventCategory {
+	if !opts.Offline {
+		return nil
+	}
+	return snapshotCategories
+}
+
+// CategoryHeader returns the display name of cat, marking categories that
+// describe the present as current, or as of the last sync when offline.
+func CategoryHeader(cat EventCategory, opts Options) string {
+	if !slices.Contains(snapshotCategories, cat) {
+		return string(cat)
+	}
+	if opts.Offline {
+		return string(cat) + " (as of last sync)"
+	}
+	return string(cat) + " (current)"
+}
+
+// Notice returns the lines that qualify the report as a whole: the note on
+// offline reports followed by any warnings. It is nil for a complete, live
+// report.
+func Notice(opts Options) []string {
+	lines := offlineNotice(opts)
+	for _, w := range opts.Warnings {
+		lines = append(lines, "Warning: "+w)
+	}
+	return lines
+}
+
+// offlineNotice returns the lines that explain an offline report, or nil.
+func offlineNotice(opts Options) []string {
+	if !opts.Offline {
+		return nil
+	}
+	syncs := make([]string, len(opts.Syncs))
+	for i, s := range opts.Syncs {
+		when := "never"
+		if !s.SyncedAt.IsZero() {
+			when = s.SyncedAt.Format("Jan 2 15:04")
+		}
+		syncs[i] = s.Account + " " + when
+	}
+	lines := []string{"Offline report from the local cache; activity after the last sync is missing."}
+	if len(syncs) > 0 {
+		lines = append(lines, "Last synced: "+strings.Join(syncs, ", ")+".")
+	}
+	var stale []string
+	for _, cat := range StaleCategories(opts) {
+		stale = append(stale, string(cat))
+	}
+	return append(lines, "May be stale: "+strings.Join(stale, ", ")+".")
+}
+
+// Generate renders events in the given format. Only the template format can
+// fail, when the template does not fit the data.
+func Generate(events []Event, since, until time.Time, format string, opts Options) (string, error) {
 	switch format {
 	case "table":
-		return generateTable(events, since, until)
+		return generateTable(events, since, until, opts), nil
 	case "json":
-		return generateJSON(events, since, until)
+		return generateJSON(events, since, until, opts), nil
+	case "markdown":
+		return generateMarkdown(events, since, until, opts), nil
+	case "template":
+		return generateTemplate(events, since, until, opts)
 	default:
-		return generateText(events, since, until)
+		return generateText(events, since, until, opts), nil
+	}
+}
+
+// Title is the report heading: the sprint name or "Standup Report",
+// followed by the date range.
+func Title(since, until time.Time, opts Options) string {
+	name := "Standup Report"
+	if opts.Sprint != "" {
+		name = opts.Sprint
 	}
+	return fmt.Sprintf("%s (%s – %s)", name, since.Format("Jan 2"), until.Format("Jan 2"))
 }
 
-func generateText(events []Event, since, until time.Time) string {
+func generateText(events []Event, since, until time.Time, opts Options) string {
 	var b strings.Builder
 
-	b.WriteString(fmt.Sprintf("Standup Report (%s – %s)\n",
-		since.Format("Jan 2"), until.Format("Jan 2")))
+	b.WriteString(Title(since, until, opts) + "\n")
 	b.WriteString(strings.Repeat("=", 40) + "\n\n")
+	if notice := Notice(opts); notice != nil {
+		b.WriteString(strings.Join(notice, "\n") + "\n\n")
+	}
 
-	grouped := groupByCategory(events)
-
-	for _, cat := range categoryOrder {
-		catEvents := grouped[cat]
-		if len(catEvents) == 0 {
-			continue
-		}
-
-		header := string(cat)
-		if cat == CategoryPendingReview {
-			header += " (current)"
-		}
-		b.WriteString(fmt.Sprintf("%s:\n", header))
-		for _, e := range catEvents {
-			action := capitalize(e.Action)
-			b.WriteString(fmt.Sprintf("  - %s %s [%s] (%s)\n",
-				action, e.Title, e.Source, e.Repo))
-		}
+	for _, g := range GroupEvents(events, opts.GroupBy, opts) {
+		writeTextGroup(&b, g, "")
 		b.WriteString("\n")
 	}
 
 	if len(events) == 0 {
 		b.WriteString("No activity found for this period.\n")
+		if len(opts.Notes) > 0 {
+			b.WriteString("\n")
+		}
 	}
+	writeTextNotes(&b, opts.Notes)
 
 	return b.String()
 }
 
-func generateTable(events []Event, _, _ time.Time) string {
+// writeTextGroup writes a group's header and its events, or its subgroups,
+// indented one level further than indent.
+func writeTextGroup(b *strings.Builder, g Group, indent string) {
+	b.WriteString(fmt.Sprintf("%s%s:\n", indent, g.Name))
+	indent += "  "
+	if g.Groups != nil {
+		for _, sub := range g.Groups {
+			writeTextGroup(b, sub, indent)
+		}
+		return
+	}
+	for _, e := range g.Events {
+		b.WriteString(fmt.Sprintf("%s- %s %s [%s] (%s)%s\n",
+			indent, ActionText(e), e.Title, SourceLabel(e), e.Repo, ticketSuffix(e.Tickets)))
+		for _, c := range e.Commits {
+			b.WriteString(fmt.Sprintf("%s    %s %s\n", indent, ShortSHA(c.SHA), c.Title))
+		}
+	}
+}
+
+func generateTable(events []Event, _, _ time.Time, opts Options) string {
 	var b strings.Builder
 
+	for _, line := range Notice(opts) {
+		b.WriteString("# " + line + "\n")
+	}
+
 	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "CATEGORY\tACTION\tTITLE\tSOURCE\tREPO\tDATE")
 
-	grouped := groupByCategory(events)
-
-	for _, cat := range categoryOrder {
-		catEvents := grouped[cat]
-		for _, e := range catEvents {
+	// Rows are ordered by the report's grouping; the columns already name
+	// every grouping key.
+	for _, e := range leafEvents(GroupEvents(events, opts.GroupBy, opts)) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			string(e.Category), ActionText(e), e.Title, SourceLabel(e), e.Repo, tableDate(e))
+		for _, c := range e.Commits {
 			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
-				string(cat), capitalize(e.Action), e.Title, e.Source, e.Repo,
-				e.CreatedAt.Format("2006-01-02"))
+				string(c.Category), Capitalize(c.Action), "↳ "+c.Title, SourceLabel(c), c.Repo,
+				c.CreatedAt.Format("2006-01-02"))
 		}
 	}
 
 	w.Flush()
+	if len(opts.Notes) > 0 {
+		b.WriteString("\n")
+		writeTextNotes(&b, opts.Notes)
+	}
 	return b.String()
 }
 
-func generateJSON(events []Event, since, until time.Time) string {
-	type jsonEvent struct {
-		Category  string `json:"category"`
-		Action    string `json:"action"`
-		Title     string `json:"title"`
+func generateJSON(events []Event, since, until time.Time, opts Options) string {
+	type jsonTicket struct {
+		Key string `json:"key"`
+		URL string `json:"url"`
+	}
+
+	type jsonOccurrence struct {
 		URL       string `json:"url"`
-		Repo      string `json:"repo"`
-		Source    string `json:"source"`
 		CreatedAt string `json:"created_at"`
 	}
 
-	type jsonReport struct {
-		Since  string      `json:"since"`
-		Until  string      `json:"until"`
-		Events []jsonEvent `json:"events"`
-	}
+	type jsonEvent struct {
+		Category  string       `json:"category"`
+		Action    string       `json:"action"`
+		Title     string       `json:"title"`
+		URL       string       `json:"url"`
+		Repo      string       `json:"repo"`
+		Source    string       `json:"source"`
+		Account   string       `json:"account,omitempty"`
+		SHA       string       `json:"sha,omitempty"`
+		Branch    string       `json:"branch,omitempty"`
+		Ticket    string       `json:"ticket,omitempty"`
+		Tickets   []jsonTicket `json:"tickets,omitempty"`
+		CreatedAt string       `json:"created_at"`
+		TimeSpent int          `json:"time_spent_seconds,omitempty"`
+		Commits   []jsonEvent  `json:"commits,omitempty"`
 
-	sorted := sortedEvents(events)
+		// Aggregated events list what was merged into them.
+		Count       int              `json:"count,omitempty"`
+		FirstAt     string           `json:"first_at,omitempty"`
+		Occurrences []jsonOccurrence `json:"occurrences,omitempty"`
+	}
 
-	je := make([]jsonEvent, len(sorted))
-	for i, e := range sorted {
-		je[i] = jsonEvent{
+	var toJSON func(e Event) jsonEvent
+	toJSON = func(e Event) jsonEvent {
+		var tickets []jsonTicket
+		for _, t := range e.Tickets {
+			tickets = append(tickets, jsonTicket{Key: t.Key, URL: t.URL})
+		}
+		var commits []jsonEvent
+		for _, c := range e.Commits {
+			commits = append(commits, toJSON(c))
+		}
+		var occurrences []jsonOccurrence
+		for _, o := range e.Occurrences {
+			occurrences = append(occurrences, jsonOccurrence{URL: o.URL, CreatedAt: o.CreatedAt.Format(time.RFC3339)})
+		}
+		je := jsonEvent{
 			Category:  string(e.Category),
 			Action:    e.Action,
 			Title:     e.Title,
 			URL:       e.URL,
 			Repo:      e.Repo,
 			Source:    e.Source,
+			Account:   e.Account,
+			SHA:       e.SHA,
+			Branch:    e.Branch,
+			Ticket:    e.Ticket,
+			Tickets:   tickets,
 			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+			TimeSpent: int(e.TotalTimeSpent().Seconds()),
+			Commits:   commits,
+		}
+		if len(occurrences) > 0 {
+			je.Count = len(occurrences)
+			je.FirstAt = e.FirstAt().Format(time.RFC3339)
+			je.Occurrences = occurrences
+		}
+		return je
+	}
+
+	type jsonSync struct {
+		Account  string `json:"account"`
+		SyncedAt string `json:"synced_at,omitempty"`
+	}
+
+	type jsonOffline struct {
+		Synced          []jsonSync `json:"synced"`
+		StaleCategories []string   `json:"stale_categories"`
+	}
+
+	type jsonNote struct {
+		Key     string   `json:"key"`
+		Heading string   `json:"heading"`
+		Items   []string `json:"items"`
+	}
+
+	type jsonGroup struct {
+		Key    string      `json:"key"`
+		Name   string      `json:"name"`
+		Events []jsonEvent `json:"events,omitempty"`
+		Groups []jsonGroup `json:"groups,omitempty"`
+	}
+
+	var toJSONGroups func(groups []Group) []jsonGroup
+	toJSONGroups = func(groups []Group) []jsonGroup {
+		jg := make([]jsonGroup, len(groups))
+		for i, g := range groups {
+			jg[i] = jsonGroup{Key: g.Key, Name: g.Name, Groups: toJSONGroups(g.Groups)}
+			if g.Groups == nil {
+				for _, e := range g.Events {
+					jg[i].Events = append(jg[i].Events, toJSON(e))
+				}
+			}
 		}
+		return jg
+	}
+
+	// Events are always listed flat; groups repeat them in the requested
+	// grouping.
+	type jsonReport struct {
+		Since    string       `json:"since"`
+		Until    string       `json:"until"`
+		Sprint   string       `json:"sprint,omitempty"`
+		Offline  *jsonOffline `json:"offline,omitempty"`
+		Warnings []string     `json:"warnings,omitempty"`
+		GroupBy  []string     `json:"group_by,omitempty"`
+		Groups   []jsonGroup  `json:"groups,omitempty"`
+		Events   []jsonEvent  `json:"events"`
+		Notes    []jsonNote   `json:"notes,omitempty"`
+	}
+
+	sorted := sortedEvents(events)
+
+	je := make([]jsonEvent, len(sorted))
+	for i, e := range sorted {
+		je[i] = toJSON(e)
 	}
 
 	r := jsonReport{
-		Since:  since.Format("2006-01-02"),
-		Until:  until.Format("2006-01-02"),
-		Events: je,
+		Since:    since.Format("2006-01-02"),
+		Until:    until.Format("2006-01-02"),
+		Sprint:   opts.Sprint,
+		Events:   je,
+		Warnings: opts.Warnings,
+	}
+	for _, n := range opts.Notes {
+		r.Notes = append(r.Notes, jsonNote{Key: n.Key(), Heading: n.Heading, Items: n.Items})
+	}
+	if len(opts.GroupBy) > 0 {
+		r.GroupBy = opts.GroupBy
+		r.Groups = toJSONGroups(GroupEvents(events, opts.GroupBy, opts))
+	}
+	if opts.Offline {
+		r.Offline = &jsonOffline{Synced: []jsonSync{}, StaleCategories: []string{}}
+		for _, s := range opts.Syncs {
+			js := jsonSync{Account: s.Account}
+			if !s.SyncedAt.IsZero() {
+				js.SyncedAt = s.SyncedAt.Format(time.RFC3339)
+			}
+			r.Offline.Synced = append(r.Offline.Synced, js)
+		}
+		for _, cat := range StaleCategories(opts) {
+			r.Offline.StaleCategories = append(r.Offline.StaleCategories, cat.Slug())
+		}
 	}
 
 	data, _ := json.MarshalIndent(r, "", "  ")
 	return string(data) + "\n"
 }
 
-// groupByCategory groups events by category and sorts each group newest-first.
+// tableDate formats the day of an event, or the span of days of an
+// aggregated event.
+func tableDate(e Event) string {
+	first, last := e.FirstAt().Format("2006-01-02"), e.CreatedAt.Format("2006-01-02")
+	if first == last {
+		return last
+	}
+	return first + ".." + last
+}
+
+// InLocation converts the times of events to loc, the report's timezone,
+// so that they are displayed and grouped into days in that zone.
+func InLocation(events []Event, loc *time.Location) []Event {
+	for i := range events {
+		events[i].CreatedAt = events[i].CreatedAt.In(loc)
+	}
+	return events
+}
+
+// DedupeCommits collapses commit events that share a SHA, which happens when
+// the same commit is reported by a hosting provider and a local repository.
+// The event with a URL is kept so that the report links to the hosted commit.
+func DedupeCommits(events []Event) []Event {
+	bySHA := make(map[string]int)
+	var out []Event
+	for _, e := range events {
+		if e.Category != CategoryCommit || e.SHA == "" {
+			out = append(out, e)
+			continue
+		}
+		if i, ok := bySHA[e.SHA]; ok {
+			if out[i].URL == "" && e.URL != "" {
+				out[i] = e
+			}
+			continue
+		}
+		bySHA[e.SHA] = len(out)
+		out = append(out, e)
+	}
+	return out
+}
+
+// ticketKeyPattern matches issue-tracker keys such as "ABC-123".
+var ticketKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[0-9]+\b`)
+
+// LinkTickets attaches a TicketRef to every pull request event whose title
+// mentions the key of a tracker event in events, such as a Jira issue.
+func LinkTickets(events []Event) []Event {
+	urls := make(map[string]string)
+	for _, e := range events {
+		if e.Ticket != "" {
+			urls[e.Ticket] = e.URL
+		}
+	}
+	if len(urls) == 0 {
+		return events
+	}
+
+	for i, e := range events {
+		switch e.Category {
+		case CategoryPR, CategoryReview, CategoryReviewComment, CategoryPendingReview:
+		default:
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, key := range ticketKeyPattern.FindAllString(e.Title, -1) {
+			url, ok := urls[key]
+			if !ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+			events[i].Tickets = append(events[i].Tickets, TicketRef{Key: key, URL: url})
+		}
+	}
+	return events
+}
+
+// SourceLabel returns the event's source, qualified with the account label
+// when there is one, e.g. "github:work".
+func SourceLabel(e Event) string {
+	if e.Account == "" {
+		return e.Source
+	}
+	return e.Source + ":" + e.Account
+}
+
+func ticketSuffix(tickets []TicketRef) string {
+	if len(tickets) == 0 {
+		return ""
+	}
+	keys := make([]string, len(tickets))
+	for i, t := range tickets {
+		keys[i] = t.Key
+	}
+	return " → " + strings.Join(keys, ", ")
+}
+
+// groupByCategory groups events by category and sorts each group newest-first,
+// after the ranked events.
 func groupByCategory(events []Event) map[EventCategory][]Event {
 	grouped := make(map[EventCategory][]Event)
 	for _, e := range events {
 		grouped[e.Category] = append(grouped[e.Category], e)
 	}
 	for _, catEvents := range grouped {
-		sort.Slice(catEvents, func(i, j int) bool {
-			return catEvents[i].CreatedAt.After(catEvents[j].CreatedAt)
+		sort.SliceStable(catEvents, func(i, j int) bool {
+			return before(catEvents[i], catEvents[j])
 		})
 	}
 	return grouped
 }
 
-// sortedEvents returns events sorted by category order, then newest-first within each category.
+// before reports whether a is listed before b in their category: ranked
+// events first, in rank order, then the others newest first.
+func before(a, b Event) bool {
+	if a.Rank != b.Rank {
+		return b.Rank == 0 || (a.Rank != 0 && a.Rank < b.Rank)
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+// sortedEvents returns events sorted by category order, then newest-first within each category,
+// after the ranked events.
 func sortedEvents(events []Event) []Event {
 	catIndex := make(map[EventCategory]int)
 	for i, cat := range categoryOrder {
@@ -159,13 +527,14 @@ func sortedEvents(events []Event) []Event {
 		if ci != cj {
 			return ci < cj
 		}
-		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+		return before(sorted[i], sorted[j])
 	})
 
 	return sorted
 }
 
-func capitalize(s string) string {
+// Capitalize upper-cases the first letter of s, for actions that start a line.
+func Capitalize(s string) string {
 	if s == "" {
 		return s
 	}
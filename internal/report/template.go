@@ -0,0 +1,209 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is the data model passed to output templates. Events appear
+// in report order: by category, newest first within each category.
+type TemplateData struct {
+	Since      time.Time
+	Until      time.Time
+	Sprint     string // sprint name when the report covers a sprint
+	Events     []Event
+	Categories []CategoryGroup // non-empty categories in report order
+	Repos      []RepoGroup     // sorted by repo name
+	Days       []DayGroup      // newest day first, then the current state
+	Sources    []string        // source labels such as "github" or "github:work", sorted
+	Groups     []Group         // grouped by --group-by, by category when not given
+	Notes      []Note          // the user's own sections, such as "Blockers"
+
+	// Offline is set for reports generated from the local cache, with the
+	// last sync of each account in Syncs and the names of the categories
+	// that may be out of date in Stale.
+	Offline bool
+	Syncs   []SyncStatus
+	Stale   []string
+
+	// Warnings explain gaps in the report, such as ranges a provider could
+	// not fully cover.
+	Warnings []string
+}
+
+// CategoryGroup holds the events of one category. Name is the display name,
+// e.g. "Code Reviews", and Slug the config identifier, e.g. "reviews".
+type CategoryGroup struct {
+	Name   string
+	Slug   string
+	Events []Event
+}
+
+// RepoGroup holds the events of one repository.
+type RepoGroup struct {
+	Repo   string
+	Events []Event
+}
+
+// DayGroup holds the events of one calendar day, with Date midnight at the
+// start of the day and Name such as "Monday, Jan 2". The events that
+// describe the present rather than a day, such as pending reviews, are in a
+// last group with a zero Date, named as in the day grouping of the report.
+type DayGroup struct {
+	Date   time.Time
+	Name   string
+	Events []Event
+}
+
+// TemplateFuncs are the helper functions available to output templates.
+var TemplateFuncs = template.FuncMap{
+	"action":     ActionText,
+	"capitalize": Capitalize,
+	"link":       markdownLink,
+	"plural":     plural,
+	"source":     SourceLabel,
+	"tickets":    ticketSuffix,
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"join":  strings.Join,
+	"short": ShortSHA,
+}
+
+// plural formats n followed by word, using the plural form when n is not 1.
+// The plural form defaults to word + "s".
+func plural(n int, word string, pluralForm ...string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", word)
+	}
+	if len(pluralForm) > 0 {
+		return fmt.Sprintf("%d %s", n, pluralForm[0])
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// builtinTemplates are the named templates shipped with the binary, keyed by
+// file name without the .tmpl extension. They are parsed at startup so a
+// broken template fails every run rather than only the runs that use it.
+var builtinTemplates = func() map[string]*template.Template {
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	templates := make(map[string]*template.Template)
+	for _, entry := range entries {
+		data, err := templateFS.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		templates[name] = template.Must(ParseTemplate(name, string(data)))
+	}
+	return templates
+}()
+
+// TemplateNames returns the names of the built-in templates, sorted.
+func TemplateNames() []string {
+	names := make([]string, 0, len(builtinTemplates))
+	for name := range builtinTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseTemplate parses text as an output template with TemplateFuncs
+// available.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(TemplateFuncs).Parse(text)
+}
+
+// LoadTemplate returns the built-in template called name, or else parses the
+// template file at that path.
+func LoadTemplate(name string) (*template.Template, error) {
+	if t, ok := builtinTemplates[name]; ok {
+		return t, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("template %q is neither a built-in template (%s) nor a readable file: %w",
+			name, strings.Join(TemplateNames(), ", "), err)
+	}
+	return ParseTemplate(path.Base(name), string(data))
+}
+
+func generateTemplate(events []Event, since, until time.Time, opts Options) (string, error) {
+	t := opts.Template
+	if t == nil {
+		return "", fmt.Errorf("template output needs --template or --template-string")
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, newTemplateData(events, since, until, opts)); err != nil {
+		return "", fmt.Errorf("executing template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// Categories groups events by category, in report order and newest first
+// within each category, leaving out empty categories.
+func Categories(events []Event) []CategoryGroup {
+	grouped := groupByCategory(events)
+	var groups []CategoryGroup
+	for _, cat := range categoryOrder {
+		if len(grouped[cat]) > 0 {
+			groups = append(groups, CategoryGroup{Name: string(cat), Slug: cat.Slug(), Events: grouped[cat]})
+		}
+	}
+	return groups
+}
+
+func newTemplateData(events []Event, since, until time.Time, opts Options) TemplateData {
+	sorted := sortedEvents(events)
+
+	data := TemplateData{
+		Since: since, Until: until, Sprint: opts.Sprint, Notes: opts.Notes, Events: sorted, Categories: Categories(events),
+		Groups:  GroupEvents(events, opts.GroupBy, opts),
+		Offline: opts.Offline, Syncs: opts.Syncs, Warnings: opts.Warnings,
+	}
+	for _, cat := range StaleCategories(opts) {
+		data.Stale = append(data.Stale, string(cat))
+	}
+
+	for _, g := range GroupEvents(events, []string{"day"}, opts) {
+		day := DayGroup{Name: g.Name, Events: g.Events}
+		if g.Key != currentKey {
+			t := g.Events[0].CreatedAt
+			day.Date = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		}
+		data.Days = append(data.Days, day)
+	}
+
+	repos := make(map[string]int)
+	sources := make(map[string]bool)
+	for _, e := range sorted {
+		if i, ok := repos[e.Repo]; ok {
+			data.Repos[i].Events = append(data.Repos[i].Events, e)
+		} else {
+			repos[e.Repo] = len(data.Repos)
+			data.Repos = append(data.Repos, RepoGroup{Repo: e.Repo, Events: []Event{e}})
+		}
+
+		if label := SourceLabel(e); !sources[label] {
+			sources[label] = true
+			data.Sources = append(data.Sources, label)
+		}
+	}
+
+	sort.SliceStable(data.Repos, func(i, j int) bool { return data.Repos[i].Repo < data.Repos[j].Repo })
+	sort.Strings(data.Sources)
+	return data
+}
@@ -0,0 +1,410 @@
+// Package review is the terminal UI of "worklog review", in which the user
+// hides, edits and reorders the lines of a report and adds notes before it
+// is rendered or published.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"worklog/internal/report"
+)
+
+// ErrCancelled is returned when the user quits without finishing the review.
+var ErrCancelled = errors.New("review cancelled")
+
+// Result is the curated report.
+type Result struct {
+	// Events are the events left shown, with the user's titles and ranked
+	// in the user's order.
+	Events []report.Event
+	Notes  []report.Note
+
+	// Hidden is the state of every reviewed line by report.Event.LineKey,
+	// to be remembered for later reports.
+	Hidden map[string]bool
+}
+
+const help = "↑/↓ move  space hide/show  e edit  J/K reorder  n add note  d delete note  enter done  q quit"
+
+type item struct {
+	event  report.Event
+	key    string // line key of the event as fetched, before any edit
+	hidden bool
+}
+
+// row is a line of the list: a heading, an event or a line of a note.
+type row struct {
+	heading string
+	item    int // index into items, or -1
+	note    int // index into notes, or -1
+	line    int // index into the note's items
+}
+
+func (r row) selectable() bool {
+	return r.heading == ""
+}
+
+// prompt is a line of text being entered, handed to done when confirmed.
+type prompt struct {
+	label string
+	text  []rune
+	done  func(text string)
+}
+
+type model struct {
+	title  string
+	items  []item // by category in report order, then in the user's order
+	notes  []report.Note
+	cursor int // index into rows
+	top    int // first row on screen
+	input  *prompt
+	status string
+
+	lastHeading string
+	finished    bool
+	cancelled   bool
+}
+
+// Run shows the review UI on the terminal of in and out until the user
+// finishes or cancels it. Events whose line keys are in hidden start
+// hidden, and notes are the notes to start with.
+func Run(in, out *os.File, title string, events []report.Event, notes []report.Note, hidden map[string]bool) (Result, error) {
+	t, err := openTerminal(in, out)
+	if err != nil {
+		return Result{}, err
+	}
+	defer t.close()
+
+	m := newModel(title, events, notes, hidden)
+	for !m.finished && !m.cancelled {
+		w, h := t.size()
+		t.draw(m.view(w, h))
+		keys, err := t.readKeys()
+		if err != nil {
+			return Result{}, err
+		}
+		for _, k := range keys {
+			m.update(k)
+		}
+	}
+	if m.cancelled {
+		return Result{}, ErrCancelled
+	}
+	return m.result(), nil
+}
+
+func newModel(title string, events []report.Event, notes []report.Note, hidden map[string]bool) *model {
+	m := &model{title: title, lastHeading: "Notes"}
+	for _, n := range notes {
+		m.notes = append(m.notes, report.Note{Heading: n.Heading, Items: slices.Clone(n.Items)})
+	}
+	for _, g := range report.Categories(events) {
+		for _, e := range g.Events {
+			key := e.LineKey()
+			m.items = append(m.items, item{event: e, key: key, hidden: hidden[key]})
+		}
+	}
+	m.moveCursor(0)
+	return m
+}
+
+func (m *model) result() Result {
+	res := Result{Notes: m.notes, Hidden: make(map[string]bool)}
+	for i, it := range m.items {
+		res.Hidden[it.key] = it.hidden
+		if !it.hidden {
+			e := it.event
+			e.Rank = i + 1
+			res.Events = append(res.Events, e)
+		}
+	}
+	return res
+}
+
+func (m *model) rows() []row {
+	var rows []row
+	var cat report.EventCategory
+	for i, it := range m.items {
+		if it.event.Category != cat || i == 0 {
+			cat = it.event.Category
+			rows = append(rows, row{heading: string(cat), item: -1, note: -1})
+		}
+		rows = append(rows, row{item: i, note: -1})
+	}
+	for n, note := range m.notes {
+		rows = append(rows, row{heading: note.Heading + " (note)", item: -1, note: -1})
+		for l := range note.Items {
+			rows = append(rows, row{item: -1, note: n, line: l})
+		}
+	}
+	return rows
+}
+
+// moveCursor moves the cursor by delta selectable rows, staying on a
+// selectable row when there is one.
+func (m *model) moveCursor(delta int) {
+	rows := m.rows()
+	if len(rows) == 0 {
+		m.cursor = 0
+		return
+	}
+	m.cursor = min(max(m.cursor, 0), len(rows)-1)
+	step := 1
+	if delta < 0 {
+		step, delta = -1, -delta
+	}
+	for moved := 0; moved < delta; {
+		next := m.cursor + step
+		for next >= 0 && next < len(rows) && !rows[next].selectable() {
+			next += step
+		}
+		if next < 0 || next >= len(rows) {
+			break
+		}
+		m.cursor = next
+		moved++
+	}
+	// Land on a selectable row, e.g. after the row under the cursor was
+	// deleted or at the start.
+	for i := m.cursor; i < len(rows); i++ {
+		if rows[i].selectable() {
+			m.cursor = i
+			return
+		}
+	}
+	for i := m.cursor; i >= 0; i-- {
+		if rows[i].selectable() {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// current returns the row under the cursor.
+func (m *model) current() (row, bool) {
+	rows := m.rows()
+	if m.cursor >= len(rows) || !rows[m.cursor].selectable() {
+		return row{}, false
+	}
+	return rows[m.cursor], true
+}
+
+func (m *model) update(k key) {
+	if m.input != nil {
+		m.updateInput(k)
+		return
+	}
+	m.status = ""
+	r, ok := m.current()
+	switch {
+	case k.name == "up" || k.r == 'k':
+		m.moveCursor(-1)
+	case k.name == "down" || k.r == 'j':
+		m.moveCursor(1)
+	case k.name == "pgup":
+		m.moveCursor(-10)
+	case k.name == "pgdown":
+		m.moveCursor(10)
+	case k.name == "home" || k.r == 'g':
+		m.moveCursor(-len(m.rows()))
+	case k.name == "end" || k.r == 'G':
+		m.moveCursor(len(m.rows()))
+	case k.r == ' ' || k.r == 'x':
+		if ok && r.item >= 0 {
+			m.items[r.item].hidden = !m.items[r.item].hidden
+		}
+	case k.r == 'K':
+		m.move(r, ok, -1)
+	case k.r == 'J':
+		m.move(r, ok, 1)
+	case k.r == 'e':
+		m.edit(r, ok)
+	case k.r == 'n':
+		m.addNote()
+	case k.r == 'd':
+		if ok && r.note >= 0 {
+			note := &m.notes[r.note]
+			note.Items = slices.Delete(note.Items, r.line, r.line+1)
+			if len(note.Items) == 0 {
+				m.notes = slices.Delete(m.notes, r.note, r.note+1)
+			}
+			m.moveCursor(0)
+		}
+	case k.name == "enter":
+		m.finished = true
+	case k.r == 'q' || k.name == "esc" || k.name == "ctrl-c":
+		m.cancelled = true
+	}
+}
+
+// move swaps the event or note line under the cursor with its neighbour
+// in the given direction, within its category or note.
+func (m *model) move(r row, ok bool, dir int) {
+	switch {
+	case !ok:
+	case r.item >= 0:
+		j := r.item + dir
+		if j < 0 || j >= len(m.items) || m.items[j].event.Category != m.items[r.item].event.Category {
+			return
+		}
+		m.items[r.item], m.items[j] = m.items[j], m.items[r.item]
+		m.cursor += dir
+	case r.note >= 0:
+		items := m.notes[r.note].Items
+		j := r.line + dir
+		if j < 0 || j >= len(items) {
+			return
+		}
+		items[r.line], items[j] = items[j], items[r.line]
+		m.cursor += dir
+	}
+}
+
+func (m *model) edit(r row, ok bool) {
+	switch {
+	case !ok:
+	case r.item >= 0:
+		e := &m.items[r.item].event
+		m.input = &prompt{label: "Title: ", text: []rune(e.Title), done: func(text string) {
+			if text != "" {
+				e.Title = text
+			}
+		}}
+	case r.note >= 0:
+		items := m.notes[r.note].Items
+		m.input = &prompt{label: "Note: ", text: []rune(items[r.line]), done: func(text string) {
+			if text != "" {
+				items[r.line] = text
+			}
+		}}
+	}
+}
+
+// addNote asks for a heading, such as "Blockers", and a line to add to the
+// note with that heading.
+func (m *model) addNote() {
+	m.input = &prompt{label: "Note heading: ", text: []rune(m.lastHeading), done: func(heading string) {
+		if heading == "" {
+			return
+		}
+		m.lastHeading = heading
+		m.input = &prompt{label: heading + ": ", done: func(text string) {
+			if text == "" {
+				return
+			}
+			m.notes = report.AddNote(m.notes, heading, text)
+			m.status = fmt.Sprintf("added to %q", heading)
+		}}
+	}}
+}
+
+func (m *model) updateInput(k key) {
+	p := m.input
+	switch {
+	case k.name == "enter":
+		m.input = nil
+		p.done(strings.TrimSpace(string(p.text)))
+	case k.name == "esc" || k.name == "ctrl-c":
+		m.input = nil
+	case k.name == "backspace":
+		if len(p.text) > 0 {
+			p.text = p.text[:len(p.text)-1]
+		}
+	case k.name == "ctrl-u":
+		p.text = nil
+	case k.name == "" && k.r >= ' ':
+		p.text = append(p.text, k.r)
+	}
+}
+
+// view renders the screen as lines for a terminal of width w and height h.
+func (m *model) view(w, h int) []string {
+	lines := []string{bold(clip("Review: "+m.title, w)), dim(clip(help, w)), ""}
+
+	rows := m.rows()
+	height := max(h-len(lines)-1, 1)
+	if m.cursor < m.top {
+		m.top = m.cursor
+	}
+	if m.cursor >= m.top+height {
+		m.top = m.cursor - height + 1
+	}
+	// Show the heading above the first event when scrolled to the top.
+	if m.top == 1 && rows[0].heading != "" {
+		m.top = 0
+	}
+	for i := m.top; i < len(rows) && i < m.top+height; i++ {
+		lines = append(lines, m.rowText(rows[i], i == m.cursor, w))
+	}
+	if len(rows) == 0 {
+		lines = append(lines, "No activity found for this period. Press n to add a note.")
+	}
+	for len(lines) < h-1 {
+		lines = append(lines, "")
+	}
+
+	switch {
+	case m.input != nil:
+		lines = append(lines, clip(m.input.label+string(m.input.text), w-1)+"█")
+	default:
+		lines = append(lines, dim(clip(m.status, w)))
+	}
+	return lines
+}
+
+func (m *model) rowText(r row, selected bool, w int) string {
+	if r.heading != "" {
+		return bold(clip(r.heading, w))
+	}
+	cursor := "  "
+	if selected {
+		cursor = "> "
+	}
+	if r.note >= 0 {
+		text := clip(cursor+"- "+m.notes[r.note].Items[r.line], w)
+		if selected {
+			return reverse(text)
+		}
+		return text
+	}
+
+	it := m.items[r.item]
+	box := "[x] "
+	if it.hidden {
+		box = "[ ] "
+	}
+	e := it.event
+	text := report.ActionText(e) + " " + e.Title + " · " + e.Repo
+	switch n := len(e.Commits); n {
+	case 0:
+	case 1:
+		text += " (+1 commit)"
+	default:
+		text += fmt.Sprintf(" (+%d commits)", n)
+	}
+	text = clip(cursor+box+text, w)
+	switch {
+	case selected:
+		return reverse(text)
+	case it.hidden:
+		return dim(text)
+	}
+	return text
+}
+
+// clip cuts s to w runes, marking the cut with an ellipsis.
+func clip(s string, w int) string {
+	runes := []rune(s)
+	if w <= 0 || len(runes) <= w {
+		return s
+	}
+	return string(runes[:w-1]) + "…"
+}
+
+func bold(s string) string    { return "\x1b[1m" + s + "\x1b[0m" }
+func dim(s string) string     { return "\x1b[2m" + s + "\x1b[0m" }
+func reverse(s string) string { return "\x1b[7m" + s + "\x1b[0m" }
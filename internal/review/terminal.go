@@ -0,0 +1,127 @@
+package review
+
+import (
+	"errors"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// terminal draws on out, which is usually stderr so that the rendered
+// report can still be redirected, and reads keys from in in raw mode.
+type terminal struct {
+	in, out *os.File
+	state   *term.State
+}
+
+// Check reports whether in and out are a terminal the review UI can run
+// on, so that callers can fail before fetching the report.
+func Check(in, out *os.File) error {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return errors.New("worklog review needs an interactive terminal")
+	}
+	return nil
+}
+
+func openTerminal(in, out *os.File) (*terminal, error) {
+	if err := Check(in, out); err != nil {
+		return nil, err
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	// Switch to the alternate screen and hide the cursor.
+	out.WriteString("\x1b[?1049h\x1b[?25l")
+	return &terminal{in: in, out: out, state: state}, nil
+}
+
+func (t *terminal) close() {
+	t.out.WriteString("\x1b[?25h\x1b[?1049l")
+	term.Restore(int(t.in.Fd()), t.state)
+}
+
+// size returns the terminal's width and height, or 80x24 if unknown.
+func (t *terminal) size() (int, int) {
+	w, h, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// draw replaces the screen with lines.
+func (t *terminal) draw(lines []string) {
+	buf := []byte("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i > 0 {
+			buf = append(buf, "\r\n"...)
+		}
+		buf = append(buf, line...)
+	}
+	t.out.Write(buf)
+}
+
+// key is a key press: a named key such as "up" or "enter", or a rune.
+type key struct {
+	name string
+	r    rune
+}
+
+var escapeKeys = map[string]string{
+	"\x1b[A": "up", "\x1b[B": "down", "\x1bOA": "up", "\x1bOB": "down",
+	"\x1b[5~": "pgup", "\x1b[6~": "pgdown", "\x1b[H": "home", "\x1b[F": "end",
+}
+
+// readKeys waits for input and returns the keys in it.
+func (t *terminal) readKeys() ([]key, error) {
+	buf := make([]byte, 256)
+	n, err := t.in.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeys(buf[:n]), nil
+}
+
+func parseKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) == 1 {
+				return append(keys, key{name: "esc"})
+			}
+			// An escape sequence runs to its final letter or "~";
+			// unknown ones are skipped.
+			end := 2
+			for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+				end++
+			}
+			end = min(end+1, len(b))
+			if name, ok := escapeKeys[string(b[:end])]; ok {
+				keys = append(keys, key{name: name})
+			}
+			b = b[end:]
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{name: "enter"})
+			b = b[1:]
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, key{name: "backspace"})
+			b = b[1:]
+		case c == 0x03:
+			keys = append(keys, key{name: "ctrl-c"})
+			b = b[1:]
+		case c == 0x15:
+			keys = append(keys, key{name: "ctrl-u"})
+			b = b[1:]
+		case c < 0x20:
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, key{r: r})
+			b = b[size:]
+		}
+	}
+	return keys
+}
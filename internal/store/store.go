@@ -0,0 +1,430 @@
+// Package store caches fetched events in a local bbolt database so that
+// later runs only fetch activity that is new since the last sync.
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"worklog/internal/report"
+)
+
+// syncOverlap is how far before the end of the synced range a sync starts
+// again. Hosting providers can take a while to show activity in their feeds,
+// and re-fetching the overlap picks up events that arrived late.
+const syncOverlap = 24 * time.Hour
+
+var (
+	eventsBucket  = []byte("events")
+	cursorsBucket = []byte("cursors")
+	metaBucket    = []byte("meta")
+	hiddenBucket  = []byte("hidden")
+
+	lastRunKey = []byte("last_run")
+)
+
+// Store is the local event cache. Events are kept per account, keyed by
+// time and ID, and a Cursor per account and scope records which range has
+// been synced. Accounts are identified by the caller, by whatever tells
+// their activity apart, such as the server and user it is read as.
+type Store struct {
+	db *bolt.DB
+}
+
+// Cursor records the contiguous range of an account's activity that has
+// been synced for one scope, and the pending reviews seen by the last sync,
+// which describe the present rather than the range.
+type Cursor struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	SyncedAt time.Time      `json:"synced_at"`
+	Pending  []report.Event `json:"pending,omitempty"`
+
+	// Warnings are the gaps in coverage reported by the syncs of the
+	// range, kept so that reports read from the cache show them too.
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Warning is a gap in coverage reported by a fetch of the range From-To.
+type Warning struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Message string    `json:"message"`
+}
+
+// WarningsBetween returns the messages of the warnings whose fetched range
+// overlaps since-until, without repeats.
+func (c Cursor) WarningsBetween(since, until time.Time) []string {
+	var msgs []string
+	for _, w := range c.Warnings {
+		if w.From.After(until) || w.To.Before(since) || slices.Contains(msgs, w.Message) {
+			continue
+		}
+		msgs = append(msgs, w.Message)
+	}
+	return msgs
+}
+
+// FetchFunc fetches an account's events between since and until, and
+// warnings about parts of the range it could not cover.
+type FetchFunc func(ctx context.Context, since, until time.Time) ([]report.Event, []string, error)
+
+// DefaultPath returns the location of the cache,
+// $XDG_CACHE_HOME/worklog/events.db or ~/.cache/worklog/events.db.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "worklog", "events.db")
+}
+
+// Open opens the store at path, creating it if needed.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another worklog process", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{eventsBucket, cursorsBucket, metaBucket, hiddenBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Fetch returns the account's events between since and until, calling fetch
+// only for the parts of the range that are not synced yet for scope, and the
+// warnings of the syncs that covered the range. The scope identifies
+// anything that narrows what fetch returns, such as a repo filter pushed
+// down to the provider, so that a narrow sync is not taken to cover a wider
+// one. Events are stored for every scope alike.
+func (s *Store) Fetch(ctx context.Context, account, scope string, fetch FetchFunc, since, until, now time.Time) ([]report.Event, []string, error) {
+	cur, ok, err := s.Cursor(account, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Missing ranges are extended to the synced range so that it stays
+	// contiguous.
+	type span struct{ from, to time.Time }
+	var spans []span
+	switch {
+	case !ok:
+		spans = append(spans, span{since, until})
+	default:
+		if since.Before(cur.From) {
+			spans = append(spans, span{since, cur.From})
+		}
+		var from time.Time
+		if until.After(cur.To) {
+			from = cur.To.Add(-syncOverlap)
+			if from.Before(cur.From) {
+				from = cur.From
+			}
+		}
+		// Unpushed commits in the range are fetched again however old,
+		// since they may have been pushed or rewritten since.
+		last := cur.To
+		if until.Before(last) {
+			last = until
+		}
+		oldest, found, err := s.oldestUnpushed(account, since, last)
+		if err != nil {
+			return nil, nil, err
+		}
+		if found && (from.IsZero() || oldest.Before(from)) {
+			from = oldest
+		}
+		if !from.IsZero() {
+			spans = append(spans, span{from, until})
+		}
+	}
+
+	for _, sp := range spans {
+		events, warnings, err := fetch(ctx, sp.from, sp.to)
+		if err != nil {
+			return nil, nil, err
+		}
+		to := sp.to
+		if to.After(now) {
+			to = now
+		}
+		if err := s.save(account, scope, events, warnings, sp.from, to, now); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	events, err := s.Events(account, scope, since, until)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cur, _, err = s.Cursor(account, scope); err != nil {
+		return nil, nil, err
+	}
+	return events, cur.WarningsBetween(since, until), nil
+}
+
+// Events returns the stored events of the account between since and until,
+// plus the pending reviews from the scope's last sync.
+func (s *Store) Events(account, scope string, since, until time.Time) ([]report.Event, error) {
+	var events []report.Event
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return scan(tx.Bucket(eventsBucket).Bucket([]byte(account)), since, until, func(_ []byte, e report.Event) {
+			events = append(events, e)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cur, _, err := s.Cursor(account, scope)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, cur.Pending...), nil
+}
+
+// oldestUnpushed returns the time of the account's oldest stored unpushed
+// commit between since and until, and whether there is one.
+func (s *Store) oldestUnpushed(account string, since, until time.Time) (time.Time, bool, error) {
+	var oldest time.Time
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return scan(tx.Bucket(eventsBucket).Bucket([]byte(account)), since, until, func(_ []byte, e report.Event) {
+			if !found && unpushed(e) {
+				oldest, found = e.CreatedAt, true
+			}
+		})
+	})
+	return oldest, found, err
+}
+
+// Cursor returns the sync cursor of the account for scope, and whether the
+// account has been synced for it.
+func (s *Store) Cursor(account, scope string) (Cursor, bool, error) {
+	var cur Cursor
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(cursorsBucket).Get(cursorKey(account, scope))
+		if v == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(v, &cur)
+	})
+	return cur, ok, err
+}
+
+// Forget deletes the account's cursor for scope, so that the next Fetch
+// syncs the whole range again. Stored events are kept and updated then.
+func (s *Store) Forget(account, scope string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cursorsBucket).Delete(cursorKey(account, scope))
+	})
+}
+
+// LastRun returns the time recorded by the last SetLastRun, and whether
+// one was recorded.
+func (s *Store) LastRun() (time.Time, bool, error) {
+	var t time.Time
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(metaBucket).Get(lastRunKey)
+		if v == nil {
+			return nil
+		}
+		ok = true
+		return t.UnmarshalText(v)
+	})
+	return t, ok, err
+}
+
+// SetLastRun records t as the time of the last successful report.
+func (s *Store) SetLastRun(t time.Time) error {
+	data, err := t.UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(lastRunKey, data)
+	})
+}
+
+// Hidden returns the keys of the report lines the user hid in worklog
+// review.
+func (s *Store) Hidden() (map[string]bool, error) {
+	hidden := make(map[string]bool)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(hiddenBucket).ForEach(func(k, _ []byte) error {
+			hidden[string(k)] = true
+			return nil
+		})
+	})
+	return hidden, err
+}
+
+// SetHidden records for each line key whether the line is hidden. Keys that
+// are not given keep their state.
+func (s *Store) SetHidden(hidden map[string]bool, now time.Time) error {
+	stamp, err := now.UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(hiddenBucket)
+		for id, h := range hidden {
+			var err error
+			if h {
+				err = b.Put([]byte(id), stamp)
+			} else {
+				err = b.Delete([]byte(id))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// save stores events, which were fetched for the range from-to, and extends
+// the scope's cursor to cover the range. Events are upserted by ID; events
+// that disappeared upstream are kept, since another scope may have fetched
+// them. Stored unpushed commits in the range are replaced by what the fetch
+// returned for them, and dropped if an unscoped fetch no longer returned
+// them at all, as after an amend or rebase. The fetch's warnings replace
+// those of earlier fetches within the range.
+func (s *Store) save(account, scope string, events []report.Event, warnings []string, from, to, now time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(eventsBucket).CreateBucketIfNotExists([]byte(account))
+		if err != nil {
+			return err
+		}
+
+		var pending []report.Event
+		fetched := make(map[string]string) // key by ID
+		for _, e := range events {
+			if e.Category == report.CategoryPendingReview {
+				pending = append(pending, e)
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			key := append(timeKey(e.CreatedAt), e.ID()...)
+			if err := b.Put(key, data); err != nil {
+				return err
+			}
+			fetched[e.ID()] = string(key)
+		}
+
+		var stale [][]byte
+		err = scan(b, from, to, func(k []byte, e report.Event) {
+			key, ok := fetched[e.ID()]
+			if unpushed(e) && key != string(k) && (ok || scope == "") {
+				stale = append(stale, k)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		cursors := tx.Bucket(cursorsBucket)
+		var cur Cursor
+		if v := cursors.Get(cursorKey(account, scope)); v != nil {
+			if err := json.Unmarshal(v, &cur); err != nil {
+				return err
+			}
+		}
+		if cur.From.IsZero() || from.Before(cur.From) {
+			cur.From = from
+		}
+		if to.After(cur.To) {
+			cur.To = to
+		}
+		cur.SyncedAt = now
+		cur.Pending = pending
+		cur.Warnings = slices.DeleteFunc(cur.Warnings, func(w Warning) bool {
+			return !w.From.Before(from) && !w.To.After(to)
+		})
+		for _, msg := range warnings {
+			cur.Warnings = append(cur.Warnings, Warning{From: from, To: to, Message: msg})
+		}
+		data, err := json.Marshal(cur)
+		if err != nil {
+			return err
+		}
+		return cursors.Put(cursorKey(account, scope), data)
+	})
+}
+
+// scan calls fn with each event in b between since and until, in time
+// order. b may be nil.
+func scan(b *bolt.Bucket, since, until time.Time, fn func(k []byte, e report.Event)) error {
+	if b == nil {
+		return nil
+	}
+	last := timeKey(until)
+	c := b.Cursor()
+	for k, v := c.Seek(timeKey(since)); k != nil && bytes.Compare(k[:len(last)], last) <= 0; k, v = c.Next() {
+		var e report.Event
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		fn(k, e)
+	}
+	return nil
+}
+
+// unpushed reports whether e is a local commit that was not pushed yet when
+// it was fetched. Unlike other events it changes later: it is pushed, or
+// replaced by another commit when amended or rebased.
+func unpushed(e report.Event) bool {
+	return e.Category == report.CategoryCommit && e.Action == "committed"
+}
+
+// timeKey encodes t so that keys sort in time order.
+func timeKey(t time.Time) []byte {
+	return []byte(t.UTC().Format("20060102T150405.000000000Z") + "/")
+}
+
+func cursorKey(account, scope string) []byte {
+	return []byte(account + "\x00" + scope)
+}
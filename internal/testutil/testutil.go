@@ -0,0 +1,69 @@
+// Package testutil holds what the package tests share: the week their
+// recorded activity falls in, loading of recorded API responses, and a
+// comparable rendering of events.
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"worklog/internal/report"
+)
+
+// Since and Until are the report range of the tests, the working week of
+// October 12, 2026.
+var (
+	Since = time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	Until = time.Date(2026, 10, 16, 23, 59, 59, 0, time.UTC)
+)
+
+// Fixtures reads a testdata file that maps request keys to recorded
+// responses. "{{server}}" in it is replaced with serverURL, for responses
+// that link back to the server, such as the next page.
+func Fixtures(t testing.TB, file, serverURL string) map[string]json.RawMessage {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(strings.ReplaceAll(string(data), "{{server}}", serverURL))
+	var fixtures map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+	return fixtures
+}
+
+// Summarize renders events as sorted lines, one per event, with every
+// field the providers set.
+func Summarize(events []report.Event) []string {
+	var lines []string
+	for _, e := range events {
+		line := fmt.Sprintf("%s | %s | %s | %s | %s | %s | %s",
+			e.Category, e.Action, e.Title, e.Repo, e.URL, e.Source, e.CreatedAt.UTC().Format(time.RFC3339))
+		if e.SHA != "" {
+			line += " | sha " + e.SHA
+		}
+		if e.Branch != "" || e.CommitSHAs != nil {
+			line += fmt.Sprintf(" | branch %q commits %v", e.Branch, e.CommitSHAs)
+		}
+		lines = append(lines, line)
+	}
+	slices.Sort(lines)
+	return lines
+}
+
+// CheckEvents reports an error unless events summarize to want, in any
+// order.
+func CheckEvents(t testing.TB, events []report.Event, want []string) {
+	t.Helper()
+	want = slices.Sorted(slices.Values(want))
+	if got := Summarize(events); !slices.Equal(got, want) {
+		t.Errorf("got events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}